@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/graph"
+	"github.com/goddhi/ucan-visualizer/internal/services/parser"
+	"github.com/goddhi/ucan-visualizer/internal/services/validator"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+type BundleHandler struct {
+	parser    *parser.Service
+	validator *validator.Service
+	graph     *graph.Service
+}
+
+func NewBundleHandler() *BundleHandler {
+	return &BundleHandler{
+		parser:    parser.NewService(),
+		validator: validator.NewService(),
+		graph:     graph.NewService(),
+	}
+}
+
+// AnalyzeBundle handles POST /api/bundle
+func (h *BundleHandler) AnalyzeBundle(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Bundle analysis request from %s", r.RemoteAddr)
+
+	var req models.ValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode request: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.Token == "" {
+		log.Printf("[WARN] Empty token in request")
+		respondError(w, http.StatusBadRequest, "Token is required", nil)
+		return
+	}
+
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
+	if err != nil {
+		log.Printf("[ERROR] Failed to normalize token: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid token format", err)
+		return
+	}
+
+	var at time.Time
+	if req.At != nil {
+		at = *req.At
+	}
+	opts, err := validationOptions(at, req.ExpiringSoon)
+	if err != nil {
+		log.Printf("[ERROR] Invalid validation options: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid validation options", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Analyzing bundle of length %d bytes", len(tokenBytes))
+	h.analyze(w, tokenBytes, opts)
+}
+
+// AnalyzeBundleFile handles POST /api/bundle/file
+func (h *BundleHandler) AnalyzeBundleFile(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Bundle analysis file upload request from %s", r.RemoteAddr)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("[ERROR] Failed to get file from form: %v", err)
+		respondError(w, http.StatusBadRequest, "File is required", err)
+		return
+	}
+	defer file.Close()
+
+	tokenBytes, err := utils.ReadUploadedFile(file, header)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read uploaded file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid file", err)
+		return
+	}
+
+	var at time.Time
+	if raw := r.FormValue("at"); raw != "" {
+		if at, err = time.Parse(time.RFC3339, raw); err != nil {
+			log.Printf("[ERROR] Invalid evaluation time: %v", err)
+			respondError(w, http.StatusBadRequest, "Invalid validation options", err)
+			return
+		}
+	}
+	opts, err := validationOptions(at, r.FormValue("expiringSoon"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid validation options: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid validation options", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Analyzing bundle file %s (%d bytes)", header.Filename, len(tokenBytes))
+	h.analyze(w, tokenBytes, opts)
+}
+
+// analyze parses the bundle once and validates and graphs each root
+func (h *BundleHandler) analyze(w http.ResponseWriter, tokenBytes []byte, opts validator.Options) {
+	bundle, err := h.parser.ParseBundle(tokenBytes)
+	if err != nil {
+		log.Printf("[ERROR] Bundle parse failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to parse bundle", err)
+		return
+	}
+
+	validations := h.validator.ValidateBundle(bundle, opts)
+	graphs, combined := h.graph.GenerateBundleGraph(bundle)
+
+	result := models.BundleResponse{
+		Roots:   make([]models.RootAnalysis, 0, len(bundle.Chains)),
+		Skipped: bundle.Skipped,
+		Graph:   combined,
+	}
+	for i, chain := range bundle.Chains {
+		result.Roots = append(result.Roots, models.RootAnalysis{
+			Root:       chain.Root,
+			Source:     chain.Source,
+			CID:        chain.DAG.Root,
+			DAG:        chain.DAG,
+			Validation: validations[i],
+			Graph:      graphs[i],
+		})
+	}
+
+	log.Printf("[INFO] Successfully analyzed bundle: %d roots, %d skipped, %d shared principals",
+		len(result.Roots), len(result.Skipped), len(combined.SharedPrincipals))
+	respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/converter"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// downloadTypes are the content type and file extension of each output
+// format when the converted bytes are downloaded
+var downloadTypes = map[string][2]string{
+	converter.FormatCAR:       {"application/vnd.ipld.car", "car"},
+	converter.FormatDAGJSON:   {"application/vnd.ipld.dag-json", "json"},
+	converter.FormatDAGCBOR:   {"application/vnd.ipld.dag-cbor", "cbor"},
+	converter.FormatJWT:       {"text/plain; charset=utf-8", "jwt"},
+	converter.FormatBase64:    {"text/plain; charset=utf-8", "txt"},
+	converter.FormatMultibase: {"text/plain; charset=utf-8", "txt"},
+}
+
+type ConvertHandler struct {
+	converter *converter.Service
+}
+
+func NewConvertHandler() *ConvertHandler {
+	return &ConvertHandler{
+		converter: converter.NewService(),
+	}
+}
+
+// Convert handles POST /api/convert
+func (h *ConvertHandler) Convert(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Convert request from %s", r.RemoteAddr)
+
+	var req models.ConvertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode request: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.Token == "" {
+		log.Printf("[WARN] Empty token in request")
+		respondError(w, http.StatusBadRequest, "Token is required", nil)
+		return
+	}
+
+	if req.To == "" {
+		log.Printf("[WARN] Missing output format in request")
+		respondError(w, http.StatusBadRequest, "Output format is required", nil)
+		return
+	}
+
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
+	if err != nil {
+		log.Printf("[ERROR] Failed to normalize token: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid token format", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Converting token of length %d bytes to %s", len(tokenBytes), req.To)
+	h.convert(w, tokenBytes, req.To, req.Base, req.Download)
+}
+
+// ConvertFile handles POST /api/convert/file
+func (h *ConvertHandler) ConvertFile(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Convert file upload request from %s", r.RemoteAddr)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("[ERROR] Failed to get file from form: %v", err)
+		respondError(w, http.StatusBadRequest, "File is required", err)
+		return
+	}
+	defer file.Close()
+
+	tokenBytes, err := utils.ReadUploadedFile(file, header)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read uploaded file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid file", err)
+		return
+	}
+
+	to := r.FormValue("to")
+	if to == "" {
+		log.Printf("[WARN] Missing output format in request")
+		respondError(w, http.StatusBadRequest, "Output format is required", nil)
+		return
+	}
+
+	var download bool
+	if raw := r.FormValue("download"); raw != "" {
+		if download, err = strconv.ParseBool(raw); err != nil {
+			log.Printf("[ERROR] Invalid download flag: %v", err)
+			respondError(w, http.StatusBadRequest, "Invalid download flag", err)
+			return
+		}
+	}
+
+	log.Printf("[DEBUG] Converting file %s (%d bytes) to %s", header.Filename, len(tokenBytes), to)
+	h.convert(w, tokenBytes, to, r.FormValue("base"), download)
+}
+
+// convert converts the token and responds with the result, or with the
+// converted bytes themselves when downloading
+func (h *ConvertHandler) convert(w http.ResponseWriter, tokenBytes []byte, to, base string, download bool) {
+	result, err := h.converter.Convert(tokenBytes, to, base)
+	if err != nil {
+		log.Printf("[ERROR] Conversion failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to convert token", err)
+		return
+	}
+
+	for _, warning := range result.Warnings {
+		log.Printf("[WARN] Conversion to %s: %s", result.To, warning)
+	}
+	log.Printf("[INFO] Successfully converted %s to %s: %d blocks", result.From, result.To, result.Blocks)
+
+	if !download {
+		respondJSON(w, http.StatusOK, result)
+		return
+	}
+
+	kind := downloadTypes[result.To]
+	w.Header().Set("Content-Type", kind[0])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "token."+kind[1]))
+	w.WriteHeader(http.StatusOK)
+	w.Write(result.Data)
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/goddhi/ucan-visualizer/internal/models"
 	"github.com/goddhi/ucan-visualizer/internal/services/graph"
@@ -37,25 +39,39 @@ func (h *GraphHandler) GenerateGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenBytes, err := utils.NormalizeToken(req.Token, req.Format)
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
 	if err != nil {
 		log.Printf("[ERROR] Failed to normalize token: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid token format", err)
 		return
 	}
 
-	log.Printf("[DEBUG] Generating delegation graph for token of length %d bytes", len(tokenBytes))
-	
-	result, err := h.graph.GenerateDelegationGraph(tokenBytes)
+	export, err := exportFormat(r, req.Export)
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
+	mode, err := graphMode(r, req.Mode)
+	if err != nil {
+		log.Printf("[ERROR] Invalid graph mode: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid graph mode", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Generating %s graph for token of length %d bytes", mode, len(tokenBytes))
+
+	result, err := h.delegationGraph(mode, tokenBytes)
 	if err != nil {
 		log.Printf("[ERROR] Graph generation failed: %v", err)
 		respondError(w, http.StatusUnprocessableEntity, "Failed to generate graph", err)
 		return
 	}
 
-	log.Printf("[INFO] Successfully generated delegation graph: %d nodes, %d edges", 
+	log.Printf("[INFO] Successfully generated delegation graph: %d nodes, %d edges",
 		len(result.Nodes), len(result.Edges))
-	respondJSON(w, http.StatusOK, result)
+	h.respondGraph(w, export, result, result.Nodes, result.Edges)
 }
 
 // GenerateGraphFile handles POST /api/graph/delegation/file
@@ -84,19 +100,33 @@ func (h *GraphHandler) GenerateGraphFile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	log.Printf("[DEBUG] Generating delegation graph for file %s (%d bytes)", 
-		header.Filename, len(tokenBytes))
-	
-	result, err := h.graph.GenerateDelegationGraph(tokenBytes)
+	export, err := exportFormat(r, r.FormValue("export"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
+	mode, err := graphMode(r, r.FormValue("mode"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid graph mode: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid graph mode", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Generating %s graph for file %s (%d bytes)",
+		mode, header.Filename, len(tokenBytes))
+
+	result, err := h.delegationGraph(mode, tokenBytes)
 	if err != nil {
 		log.Printf("[ERROR] Graph generation failed: %v", err)
 		respondError(w, http.StatusUnprocessableEntity, "Failed to generate graph", err)
 		return
 	}
 
-	log.Printf("[INFO] Successfully generated delegation graph from file: %d nodes, %d edges", 
+	log.Printf("[INFO] Successfully generated delegation graph from file: %d nodes, %d edges",
 		len(result.Nodes), len(result.Edges))
-	respondJSON(w, http.StatusOK, result)
+	h.respondGraph(w, export, result, result.Nodes, result.Edges)
 }
 
 // GenerateInvocationGraph handles POST /api/graph/invocation
@@ -116,13 +146,20 @@ func (h *GraphHandler) GenerateInvocationGraph(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	tokenBytes, err := utils.NormalizeToken(req.Token, req.Format)
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
 	if err != nil {
 		log.Printf("[ERROR] Failed to normalize token: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid token format", err)
 		return
 	}
 
+	export, err := exportFormat(r, req.Export)
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
 	log.Printf("[DEBUG] Generating invocation graph for token of length %d bytes", len(tokenBytes))
 	
 	result, err := h.graph.GenerateInvocationGraph(tokenBytes)
@@ -134,7 +171,7 @@ func (h *GraphHandler) GenerateInvocationGraph(w http.ResponseWriter, r *http.Re
 
 	log.Printf("[INFO] Successfully generated invocation graph: %d nodes, %d edges, is_invocation=%v", 
 		len(result.Nodes), len(result.Edges), result.IsInvocation)
-	respondJSON(w, http.StatusOK, result)
+	h.respondGraph(w, export, result, result.Nodes, result.Edges)
 }
 
 // GenerateInvocationGraphFile handles POST /api/graph/invocation/file
@@ -163,6 +200,13 @@ func (h *GraphHandler) GenerateInvocationGraphFile(w http.ResponseWriter, r *htt
 		return
 	}
 
+	export, err := exportFormat(r, r.FormValue("export"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
 	log.Printf("[DEBUG] Generating invocation graph for file %s (%d bytes)", 
 		header.Filename, len(tokenBytes))
 	
@@ -175,5 +219,271 @@ func (h *GraphHandler) GenerateInvocationGraphFile(w http.ResponseWriter, r *htt
 
 	log.Printf("[INFO] Successfully generated invocation graph from file: %d nodes, %d edges, is_invocation=%v", 
 		len(result.Nodes), len(result.Edges), result.IsInvocation)
-	respondJSON(w, http.StatusOK, result)
-}
\ No newline at end of file
+	h.respondGraph(w, export, result, result.Nodes, result.Edges)
+}
+
+// GenerateReceiptGraph handles POST /api/graph/receipt
+func (h *GraphHandler) GenerateReceiptGraph(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Receipt graph generation request from %s", r.RemoteAddr)
+
+	var req models.GraphRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode request: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.Token == "" {
+		log.Printf("[WARN] Empty token in request")
+		respondError(w, http.StatusBadRequest, "Token is required", nil)
+		return
+	}
+
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
+	if err != nil {
+		log.Printf("[ERROR] Failed to normalize token: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid token format", err)
+		return
+	}
+
+	export, err := exportFormat(r, req.Export)
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Generating receipt graph for token of length %d bytes", len(tokenBytes))
+
+	result, err := h.graph.GenerateReceiptGraph(tokenBytes)
+	if err != nil {
+		log.Printf("[ERROR] Receipt graph generation failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to generate receipt graph", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully generated receipt graph: %d nodes, %d edges, success=%v",
+		len(result.Nodes), len(result.Edges), result.Receipt.Out.Success)
+	h.respondGraph(w, export, result, result.Nodes, result.Edges)
+}
+
+// GenerateReceiptGraphFile handles POST /api/graph/receipt/file
+func (h *GraphHandler) GenerateReceiptGraphFile(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Receipt graph file generation request from %s", r.RemoteAddr)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("[ERROR] Failed to get file from form: %v", err)
+		respondError(w, http.StatusBadRequest, "File is required", err)
+		return
+	}
+	defer file.Close()
+
+	tokenBytes, err := utils.ReadUploadedFile(file, header)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read uploaded file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid file", err)
+		return
+	}
+
+	// Validate file content
+	if err := utils.IsValidUCANFile(tokenBytes, header.Filename); err != nil {
+		log.Printf("[ERROR] Invalid UCAN file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid UCAN file", err)
+		return
+	}
+
+	export, err := exportFormat(r, r.FormValue("export"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Generating receipt graph for file %s (%d bytes)",
+		header.Filename, len(tokenBytes))
+
+	result, err := h.graph.GenerateReceiptGraph(tokenBytes)
+	if err != nil {
+		log.Printf("[ERROR] Receipt graph generation failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to generate receipt graph", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully generated receipt graph from file: %d nodes, %d edges, success=%v",
+		len(result.Nodes), len(result.Edges), result.Receipt.Out.Success)
+	h.respondGraph(w, export, result, result.Nodes, result.Edges)
+}
+
+// TraceCapability handles POST /api/graph/trace
+func (h *GraphHandler) TraceCapability(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Capability trace request from %s", r.RemoteAddr)
+
+	var req models.TraceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode request: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.Token == "" {
+		log.Printf("[WARN] Empty token in request")
+		respondError(w, http.StatusBadRequest, "Token is required", nil)
+		return
+	}
+
+	if req.Can == "" {
+		log.Printf("[WARN] Empty ability in request")
+		respondError(w, http.StatusBadRequest, "Ability (can) is required", nil)
+		return
+	}
+
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
+	if err != nil {
+		log.Printf("[ERROR] Failed to normalize token: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid token format", err)
+		return
+	}
+
+	export, err := exportFormat(r, req.Export)
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
+	h.traceCapability(w, export, tokenBytes, req.Principal, models.CapabilityInfo{Can: req.Can, With: req.With})
+}
+
+// TraceCapabilityFile handles POST /api/graph/trace/file
+func (h *GraphHandler) TraceCapabilityFile(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Capability trace file request from %s", r.RemoteAddr)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("[ERROR] Failed to get file from form: %v", err)
+		respondError(w, http.StatusBadRequest, "File is required", err)
+		return
+	}
+	defer file.Close()
+
+	tokenBytes, err := utils.ReadUploadedFile(file, header)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read uploaded file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid file", err)
+		return
+	}
+
+	// Validate file content
+	if err := utils.IsValidUCANFile(tokenBytes, header.Filename); err != nil {
+		log.Printf("[ERROR] Invalid UCAN file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid UCAN file", err)
+		return
+	}
+
+	if r.FormValue("can") == "" {
+		log.Printf("[WARN] Empty ability in request")
+		respondError(w, http.StatusBadRequest, "Ability (can) is required", nil)
+		return
+	}
+
+	export, err := exportFormat(r, r.FormValue("export"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid export format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid export format", err)
+		return
+	}
+
+	h.traceCapability(w, export, tokenBytes, r.FormValue("principal"),
+		models.CapabilityInfo{Can: r.FormValue("can"), With: r.FormValue("with")})
+}
+
+// traceCapability traces one capability of a token and sends the result
+func (h *GraphHandler) traceCapability(w http.ResponseWriter, export string, tokenBytes []byte, principal string, query models.CapabilityInfo) {
+	log.Printf("[DEBUG] Tracing %s on %q to %q in token of length %d bytes",
+		query.Can, query.With, principal, len(tokenBytes))
+
+	result, err := h.graph.TraceCapability(tokenBytes, principal, query)
+	if err != nil {
+		log.Printf("[ERROR] Capability trace failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to trace capability", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully traced %s: granted=%v, %d paths, %d edges",
+		query.Can, result.Granted, len(result.Paths), len(result.Edges))
+	h.respondGraph(w, export, result, result.Nodes, result.Edges)
+}
+
+// graphMode picks the mode of a delegation graph request: the mode
+// parameter if given, else principals
+func graphMode(r *http.Request, requested string) (string, error) {
+	if requested == "" {
+		requested = r.URL.Query().Get("mode")
+	}
+	switch mode := strings.ToLower(requested); mode {
+	case "", graph.ModePrincipals:
+		return graph.ModePrincipals, nil
+	case graph.ModeDelegations:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported graph mode: %q", requested)
+	}
+}
+
+// delegationGraph generates the delegation graph of a token in the given mode
+func (h *GraphHandler) delegationGraph(mode string, tokenBytes []byte) (*models.GraphResponse, error) {
+	if mode == graph.ModeDelegations {
+		return h.graph.GenerateProofGraph(tokenBytes)
+	}
+	return h.graph.GenerateDelegationGraph(tokenBytes)
+}
+
+// exportFormat picks the export format of a graph request: the export
+// parameter if given, else the first Accept media type naming a format,
+// else JSON
+func exportFormat(r *http.Request, requested string) (string, error) {
+	if requested == "" {
+		requested = r.URL.Query().Get("export")
+	}
+	if requested != "" {
+		format := strings.ToLower(requested)
+		if _, ok := graph.ExportMediaTypes[format]; !ok {
+			return "", fmt.Errorf("unsupported export format: %q", requested)
+		}
+		return format, nil
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accepted, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		for format, exported := range graph.ExportMediaTypes {
+			if mediaType == exported {
+				return format, nil
+			}
+		}
+	}
+	return graph.ExportJSON, nil
+}
+
+// respondGraph sends a graph response as JSON, or its nodes and edges in
+// the requested export format
+func (h *GraphHandler) respondGraph(w http.ResponseWriter, export string, result interface{}, nodes []models.GraphNode, edges []models.GraphEdge) {
+	w.Header().Add("Vary", "Accept")
+	if export == graph.ExportJSON {
+		respondJSON(w, http.StatusOK, result)
+		return
+	}
+
+	data, err := h.graph.Export(export, nodes, edges)
+	if err != nil {
+		log.Printf("[ERROR] Graph export failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to export graph", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Exported graph as %s (%d bytes)", export, len(data))
+	w.Header().Set("Content-Type", graph.ExportMediaTypes[export])
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
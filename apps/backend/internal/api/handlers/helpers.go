@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"time"
+
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
 )
 
+// TokenEncodingHeader reports how the token in a JSON request was encoded
+const TokenEncodingHeader = "X-Token-Encoding"
+
 // HealthCheck handles GET /health
 func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
@@ -17,6 +23,19 @@ func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, response)
 }
 
+// decodeToken decodes the token of a JSON request and reports the encoding
+// it was found in through the TokenEncodingHeader response header
+func decodeToken(w http.ResponseWriter, token, format string) ([]byte, error) {
+	tokenBytes, encoding, err := utils.DecodeToken(token, format)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[DEBUG] Token decoded as %s", encoding)
+	w.Header().Set(TokenEncodingHeader, encoding.String())
+	return tokenBytes, nil
+}
+
 // respondJSON sends a JSON response
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
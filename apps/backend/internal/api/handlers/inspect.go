@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/parser"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+type InspectHandler struct {
+	parser *parser.Service
+}
+
+func NewInspectHandler() *InspectHandler {
+	return &InspectHandler{
+		parser: parser.NewService(),
+	}
+}
+
+// InspectCAR handles POST /api/inspect/car
+func (h *InspectHandler) InspectCAR(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] CAR inspection request from %s", r.RemoteAddr)
+
+	var req models.ParseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode request: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.Token == "" {
+		log.Printf("[WARN] Empty token in request")
+		respondError(w, http.StatusBadRequest, "Token is required", nil)
+		return
+	}
+
+	carBytes, err := decodeToken(w, req.Token, req.Format)
+	if err != nil {
+		log.Printf("[ERROR] Failed to normalize token: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid token format", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Inspecting CAR of length %d bytes", len(carBytes))
+
+	result, err := h.parser.InspectCAR(carBytes)
+	if err != nil {
+		log.Printf("[ERROR] CAR inspection failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to read CAR", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully inspected CAR: %d blocks, %d delegations",
+		len(result.Blocks), len(result.Delegations))
+	respondJSON(w, http.StatusOK, result)
+}
+
+// InspectCARFile handles POST /api/inspect/car/file
+func (h *InspectHandler) InspectCARFile(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] CAR inspection file upload request from %s", r.RemoteAddr)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("[ERROR] Failed to get file from form: %v", err)
+		respondError(w, http.StatusBadRequest, "File is required", err)
+		return
+	}
+	defer file.Close()
+
+	carBytes, err := utils.ReadUploadedFile(file, header)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read uploaded file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid file", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Inspecting CAR file %s (%d bytes)", header.Filename, len(carBytes))
+
+	result, err := h.parser.InspectCAR(carBytes)
+	if err != nil {
+		log.Printf("[ERROR] CAR inspection failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to read CAR", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully inspected CAR from file: %d blocks, %d delegations",
+		len(result.Blocks), len(result.Delegations))
+	respondJSON(w, http.StatusOK, result)
+}
@@ -25,7 +25,7 @@ func NewParseHandler() *ParseHandler {
 func (h *ParseHandler) ParseDelegation(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Parse delegation request from %s", r.RemoteAddr)
 
-	tokenBytes, err := h.extractTokenFromRequest(r)
+	tokenBytes, err := h.extractTokenFromRequest(w, r)
 	if err != nil {
 		log.Printf("[ERROR] Failed to extract token: %v", err)
 		respondError(w, http.StatusBadRequest, err.Error(), err)
@@ -49,7 +49,7 @@ func (h *ParseHandler) ParseDelegation(w http.ResponseWriter, r *http.Request) {
 func (h *ParseHandler) ParseChain(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Parse chain request from %s", r.RemoteAddr)
 
-	tokenBytes, err := h.extractTokenFromRequest(r)
+	tokenBytes, err := h.extractTokenFromRequest(w, r)
 	if err != nil {
 		log.Printf("[ERROR] Failed to extract token: %v", err)
 		respondError(w, http.StatusBadRequest, err.Error(), err)
@@ -65,7 +65,7 @@ func (h *ParseHandler) ParseChain(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[INFO] Successfully parsed delegation chain: %d delegations", len(result))
+	log.Printf("[INFO] Successfully parsed delegation chain: %d delegations", len(result.Delegations))
 	respondJSON(w, http.StatusOK, result)
 }
 
@@ -73,7 +73,7 @@ func (h *ParseHandler) ParseChain(w http.ResponseWriter, r *http.Request) {
 func (h *ParseHandler) ParseInvocation(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Parse invocation request from %s", r.RemoteAddr)
 
-	tokenBytes, err := h.extractTokenFromRequest(r)
+	tokenBytes, err := h.extractTokenFromRequest(w, r)
 	if err != nil {
 		log.Printf("[ERROR] Failed to extract token: %v", err)
 		respondError(w, http.StatusBadRequest, err.Error(), err)
@@ -144,7 +144,7 @@ func (h *ParseHandler) ParseChainFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[INFO] Successfully parsed delegation chain from file: %d delegations", len(result))
+	log.Printf("[INFO] Successfully parsed delegation chain from file: %d delegations", len(result.Delegations))
 	respondJSON(w, http.StatusOK, result)
 }
 
@@ -172,8 +172,57 @@ func (h *ParseHandler) ParseInvocationFile(w http.ResponseWriter, r *http.Reques
 	respondJSON(w, http.StatusOK, result)
 }
 
+// ParseReceipt handles POST /api/parse/receipt
+func (h *ParseHandler) ParseReceipt(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Parse receipt request from %s", r.RemoteAddr)
+
+	tokenBytes, err := h.extractTokenFromRequest(w, r)
+	if err != nil {
+		log.Printf("[ERROR] Failed to extract token: %v", err)
+		respondError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	log.Printf("[DEBUG] Processing receipt of length %d bytes", len(tokenBytes))
+
+	result, err := h.parser.ParseReceipt(tokenBytes)
+	if err != nil {
+		log.Printf("[ERROR] Receipt parse failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to parse receipt", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully parsed receipt: %s, ran=%s, success=%v",
+		result.CID, result.Ran.CID, result.Out.Success)
+	respondJSON(w, http.StatusOK, result)
+}
+
+// ParseReceiptFile handles POST /api/parse/receipt/file
+func (h *ParseHandler) ParseReceiptFile(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Parse receipt file upload request from %s", r.RemoteAddr)
+
+	tokenBytes, err := h.extractTokenFromFile(r)
+	if err != nil {
+		log.Printf("[ERROR] Failed to extract token from file: %v", err)
+		respondError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	log.Printf("[DEBUG] Processing receipt file of length %d bytes", len(tokenBytes))
+
+	result, err := h.parser.ParseReceipt(tokenBytes)
+	if err != nil {
+		log.Printf("[ERROR] Receipt parse failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to parse receipt", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully parsed receipt from file: %s, success=%v", result.CID, result.Out.Success)
+	respondJSON(w, http.StatusOK, result)
+}
+
 // extractTokenFromRequest extracts token from JSON request body
-func (h *ParseHandler) extractTokenFromRequest(r *http.Request) ([]byte, error) {
+func (h *ParseHandler) extractTokenFromRequest(w http.ResponseWriter, r *http.Request) ([]byte, error) {
 	var req models.ParseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		return nil, fmt.Errorf("invalid request body: %w", err)
@@ -184,7 +233,7 @@ func (h *ParseHandler) extractTokenFromRequest(r *http.Request) ([]byte, error)
 	}
 
 	// Normalize token - primarily for JWT format
-	tokenBytes, err := utils.NormalizeToken(req.Token, req.Format)
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token format: %w", err)
 	}
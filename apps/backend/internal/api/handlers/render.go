@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/graph"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+type RenderHandler struct {
+	graph *graph.Service
+}
+
+func NewRenderHandler() *RenderHandler {
+	return &RenderHandler{
+		graph: graph.NewService(),
+	}
+}
+
+// RenderDelegation handles POST /api/render/delegation
+func (h *RenderHandler) RenderDelegation(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Render request from %s", r.RemoteAddr)
+
+	var req models.RenderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[ERROR] Failed to decode request: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if req.Token == "" {
+		log.Printf("[WARN] Empty token in request")
+		respondError(w, http.StatusBadRequest, "Token is required", nil)
+		return
+	}
+
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
+	if err != nil {
+		log.Printf("[ERROR] Failed to normalize token: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid token format", err)
+		return
+	}
+
+	image, err := imageFormat(r, req.Image)
+	if err != nil {
+		log.Printf("[ERROR] Invalid image format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid image format", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Rendering delegation graph for token of length %d bytes as %s", len(tokenBytes), image)
+	h.render(w, tokenBytes, image)
+}
+
+// RenderDelegationFile handles POST /api/render/delegation/file
+func (h *RenderHandler) RenderDelegationFile(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INFO] Render file upload request from %s", r.RemoteAddr)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("[ERROR] Failed to get file from form: %v", err)
+		respondError(w, http.StatusBadRequest, "File is required", err)
+		return
+	}
+	defer file.Close()
+
+	tokenBytes, err := utils.ReadUploadedFile(file, header)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read uploaded file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid file", err)
+		return
+	}
+
+	// Validate file content
+	if err := utils.IsValidUCANFile(tokenBytes, header.Filename); err != nil {
+		log.Printf("[ERROR] Invalid UCAN file: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid UCAN file", err)
+		return
+	}
+
+	image, err := imageFormat(r, r.FormValue("image"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid image format: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid image format", err)
+		return
+	}
+
+	log.Printf("[DEBUG] Rendering delegation graph for file %s (%d bytes) as %s", header.Filename, len(tokenBytes), image)
+	h.render(w, tokenBytes, image)
+}
+
+// render draws the delegation graph of a token and sends the image
+func (h *RenderHandler) render(w http.ResponseWriter, tokenBytes []byte, image string) {
+	result, err := h.graph.GenerateDelegationGraph(tokenBytes)
+	if err != nil {
+		log.Printf("[ERROR] Graph generation failed: %v", err)
+		respondError(w, http.StatusUnprocessableEntity, "Failed to generate graph", err)
+		return
+	}
+
+	data, err := h.graph.Render(image, result.Nodes, result.Edges)
+	if err != nil {
+		log.Printf("[ERROR] Graph rendering failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to render graph", err)
+		return
+	}
+
+	log.Printf("[INFO] Successfully rendered delegation graph as %s: %d nodes, %d edges, %d bytes",
+		image, len(result.Nodes), len(result.Edges), len(data))
+	w.Header().Set("Content-Type", graph.RenderMediaTypes[image])
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// imageFormat picks the image format of a render request: the image
+// parameter if given, else image/png if the Accept header asks for it,
+// else SVG
+func imageFormat(r *http.Request, requested string) (string, error) {
+	if requested == "" {
+		requested = r.URL.Query().Get("image")
+	}
+	if requested != "" {
+		format := strings.ToLower(requested)
+		if _, ok := graph.RenderMediaTypes[format]; !ok {
+			return "", fmt.Errorf("unsupported image format: %q", requested)
+		}
+		return format, nil
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accepted, ";")
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case graph.RenderMediaTypes[graph.RenderPNG]:
+			return graph.RenderPNG, nil
+		case graph.RenderMediaTypes[graph.RenderSVG]:
+			return graph.RenderSVG, nil
+		}
+	}
+	return graph.RenderSVG, nil
+}
@@ -22,6 +22,8 @@ func RootHandler(w http.ResponseWriter, r *http.Request) {
 				"chain_file":      "POST /api/parse/chain/file",
 				"invocation":      "POST /api/parse/invocation",
 				"invocation_file": "POST /api/parse/invocation/file",
+				"receipt":         "POST /api/parse/receipt",
+				"receipt_file":    "POST /api/parse/receipt/file",
 			},
 			"validate": map[string]string{
 				"chain":      "POST /api/validate/chain",
@@ -32,6 +34,26 @@ func RootHandler(w http.ResponseWriter, r *http.Request) {
 				"delegation_file": "POST /api/graph/delegation/file",
 				"invocation":      "POST /api/graph/invocation",
 				"invocation_file": "POST /api/graph/invocation/file",
+				"receipt":         "POST /api/graph/receipt",
+				"receipt_file":    "POST /api/graph/receipt/file",
+				"trace":           "POST /api/graph/trace",
+				"trace_file":      "POST /api/graph/trace/file",
+			},
+			"render": map[string]string{
+				"delegation":      "POST /api/render/delegation",
+				"delegation_file": "POST /api/render/delegation/file",
+			},
+			"inspect": map[string]string{
+				"car":      "POST /api/inspect/car",
+				"car_file": "POST /api/inspect/car/file",
+			},
+			"bundle": map[string]string{
+				"analyze":      "POST /api/bundle",
+				"analyze_file": "POST /api/bundle/file",
+			},
+			"convert": map[string]string{
+				"convert":      "POST /api/convert",
+				"convert_file": "POST /api/convert/file",
 			},
 		},
 	}
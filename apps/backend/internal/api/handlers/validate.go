@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/goddhi/ucan-visualizer/internal/models"
 	"github.com/goddhi/ucan-visualizer/internal/services/validator"
@@ -37,16 +39,27 @@ func (h *ValidateHandler) ValidateChain(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	tokenBytes, err := utils.NormalizeToken(req.Token, req.Format)
+	tokenBytes, err := decodeToken(w, req.Token, req.Format)
 	if err != nil {
 		log.Printf("[ERROR] Failed to normalize token: %v", err)
 		respondError(w, http.StatusBadRequest, "Invalid token format", err)
 		return
 	}
 
+	var at time.Time
+	if req.At != nil {
+		at = *req.At
+	}
+	opts, err := validationOptions(at, req.ExpiringSoon)
+	if err != nil {
+		log.Printf("[ERROR] Invalid validation options: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid validation options", err)
+		return
+	}
+
 	log.Printf("[DEBUG] Validating chain for token of length %d bytes", len(tokenBytes))
 
-	result, err := h.validator.ValidateChain(tokenBytes)
+	result, err := h.validator.ValidateChainWithOptions(tokenBytes, opts)
 	if err != nil {
 		log.Printf("[ERROR] Validation failed: %v", err)
 		respondError(w, http.StatusInternalServerError, "Validation failed", err)
@@ -84,10 +97,25 @@ func (h *ValidateHandler) ValidateFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var at time.Time
+	if raw := r.FormValue("at"); raw != "" {
+		if at, err = time.Parse(time.RFC3339, raw); err != nil {
+			log.Printf("[ERROR] Invalid evaluation time: %v", err)
+			respondError(w, http.StatusBadRequest, "Invalid validation options", err)
+			return
+		}
+	}
+	opts, err := validationOptions(at, r.FormValue("expiringSoon"))
+	if err != nil {
+		log.Printf("[ERROR] Invalid validation options: %v", err)
+		respondError(w, http.StatusBadRequest, "Invalid validation options", err)
+		return
+	}
+
 	log.Printf("[DEBUG] Validating chain for file %s (%d bytes)", 
 		header.Filename, len(tokenBytes))
 
-	result, err := h.validator.ValidateChain(tokenBytes)
+	result, err := h.validator.ValidateChainWithOptions(tokenBytes, opts)
 	if err != nil {
 		log.Printf("[ERROR] Validation failed: %v", err)
 		respondError(w, http.StatusInternalServerError, "Validation failed", err)
@@ -97,4 +125,22 @@ func (h *ValidateHandler) ValidateFile(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Successfully validated chain from file: valid=%v, total_links=%d, valid_links=%d", 
 		result.Valid, result.Summary.TotalLinks, result.Summary.ValidLinks)
 	respondJSON(w, http.StatusOK, result)
-}
\ No newline at end of file
+}
+
+// validationOptions builds validator options from request parameters
+func validationOptions(at time.Time, expiringSoon string) (validator.Options, error) {
+	opts := validator.Options{At: at}
+
+	if expiringSoon != "" {
+		window, err := time.ParseDuration(expiringSoon)
+		if err != nil {
+			return opts, fmt.Errorf("invalid expiringSoon duration: %w", err)
+		}
+		if window < 0 {
+			return opts, fmt.Errorf("expiringSoon must not be negative")
+		}
+		opts.ExpiringSoonWindow = window
+	}
+
+	return opts, nil
+}
@@ -16,6 +16,10 @@ func SetupRouter() http.Handler {
 	parseHandler := handlers.NewParseHandler()
 	validateHandler := handlers.NewValidateHandler()
 	graphHandler := handlers.NewGraphHandler()
+	inspectHandler := handlers.NewInspectHandler()
+	bundleHandler := handlers.NewBundleHandler()
+	convertHandler := handlers.NewConvertHandler()
+	renderHandler := handlers.NewRenderHandler()
 
 	r.HandleFunc("/", handlers.RootHandler).Methods("GET")
 
@@ -33,6 +37,8 @@ func SetupRouter() http.Handler {
 	api.HandleFunc("/parse/chain/file", parseHandler.ParseChainFile).Methods("POST")
 	api.HandleFunc("/parse/invocation", parseHandler.ParseInvocation).Methods("POST")
 	api.HandleFunc("/parse/invocation/file", parseHandler.ParseInvocationFile).Methods("POST")
+	api.HandleFunc("/parse/receipt", parseHandler.ParseReceipt).Methods("POST")
+	api.HandleFunc("/parse/receipt/file", parseHandler.ParseReceiptFile).Methods("POST")
 
 	// Validate endpoints
 	api.HandleFunc("/validate/chain", validateHandler.ValidateChain).Methods("POST")
@@ -43,11 +49,32 @@ func SetupRouter() http.Handler {
 	api.HandleFunc("/graph/delegation/file", graphHandler.GenerateGraphFile).Methods("POST")
 	api.HandleFunc("/graph/invocation", graphHandler.GenerateInvocationGraph).Methods("POST")
 	api.HandleFunc("/graph/invocation/file", graphHandler.GenerateInvocationGraphFile).Methods("POST")
+	api.HandleFunc("/graph/receipt", graphHandler.GenerateReceiptGraph).Methods("POST")
+	api.HandleFunc("/graph/receipt/file", graphHandler.GenerateReceiptGraphFile).Methods("POST")
+	api.HandleFunc("/graph/trace", graphHandler.TraceCapability).Methods("POST")
+	api.HandleFunc("/graph/trace/file", graphHandler.TraceCapabilityFile).Methods("POST")
+
+	// Render endpoints
+	api.HandleFunc("/render/delegation", renderHandler.RenderDelegation).Methods("POST")
+	api.HandleFunc("/render/delegation/file", renderHandler.RenderDelegationFile).Methods("POST")
+
+	// Inspect endpoints
+	api.HandleFunc("/inspect/car", inspectHandler.InspectCAR).Methods("POST")
+	api.HandleFunc("/inspect/car/file", inspectHandler.InspectCARFile).Methods("POST")
+
+	// Bundle endpoints
+	api.HandleFunc("/bundle", bundleHandler.AnalyzeBundle).Methods("POST")
+	api.HandleFunc("/bundle/file", bundleHandler.AnalyzeBundleFile).Methods("POST")
+
+	// Convert endpoints
+	api.HandleFunc("/convert", convertHandler.Convert).Methods("POST")
+	api.HandleFunc("/convert/file", convertHandler.ConvertFile).Methods("POST")
 
 	cors := gorillahandlers.CORS(
 		gorillahandlers.AllowedOrigins([]string{"*"}),
 		gorillahandlers.AllowedMethods([]string{"GET", "POST", "OPTIONS"}),
 		gorillahandlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
+		gorillahandlers.ExposedHeaders([]string{handlers.TokenEncodingHeader}),
 	)
 
 	return cors(r)
@@ -0,0 +1,48 @@
+package models
+
+// Bundle is a token holding several root delegations or invocations, such
+// as a multi-root CAR or an agent message executing several tasks. Each root
+// is parsed into its own DAG.
+type Bundle struct {
+	Chains  []BundleChain `json:"chains"`
+	Skipped []SkippedRoot `json:"skipped,omitempty"`
+}
+
+// BundleChain is the proof DAG of one root of a bundle
+type BundleChain struct {
+	Root   string         `json:"root"`   // CAR root the chain was reached through
+	Source string         `json:"source"` // archive, delegation, agent_message, token
+	DAG    *DelegationDAG `json:"dag"`
+}
+
+// SkippedRoot is a CAR root that holds no delegation
+type SkippedRoot struct {
+	CID    string `json:"cid"`
+	Reason string `json:"reason"`
+}
+
+// BundleResponse analyses every root of a bundle on its own and merges
+// their graphs
+type BundleResponse struct {
+	Roots   []RootAnalysis `json:"roots"`
+	Skipped []SkippedRoot  `json:"skipped,omitempty"`
+	Graph   CombinedGraph  `json:"graph"`
+}
+
+// RootAnalysis is the parse, validation and graph of one bundle root
+type RootAnalysis struct {
+	Root       string            `json:"root"`
+	Source     string            `json:"source"`
+	CID        string            `json:"cid"` // root delegation of the chain
+	DAG        *DelegationDAG    `json:"dag"`
+	Validation *ValidationResult `json:"validation"`
+	Graph      *GraphResponse    `json:"graph"`
+}
+
+// CombinedGraph merges the graphs of several roots. A principal appearing
+// under more than one root is a single node listing every root it is in.
+type CombinedGraph struct {
+	Nodes            []GraphNode `json:"nodes"`
+	Edges            []GraphEdge `json:"edges"`
+	SharedPrincipals []string    `json:"sharedPrincipals"`
+}
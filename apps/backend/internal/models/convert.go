@@ -0,0 +1,24 @@
+package models
+
+// ConvertRequest asks for a token to be re-emitted in another format
+type ConvertRequest struct {
+	Token  string `json:"token"`
+	Format string `json:"format,omitempty"` // encoding of token, as for /api/parse
+	To     string `json:"to"`               // car, dag-json, dag-cbor, jwt, base64, multibase
+	// Base is the multibase used when To is "multibase", e.g. "base32"
+	Base string `json:"base,omitempty"`
+	// Download returns the converted bytes as the response body
+	Download bool `json:"download,omitempty"`
+}
+
+// ConvertResponse is a token in its new format
+type ConvertResponse struct {
+	From     string   `json:"from"` // car, dag-json, dag-cbor, jwt
+	To       string   `json:"to"`
+	Output   string   `json:"output"`
+	Encoding string   `json:"encoding,omitempty"` // "base64" when output holds binary data
+	Roots    []string `json:"roots"`              // root CIDs of the converted token
+	Blocks   int      `json:"blocks"`             // blocks carried by the output
+	Warnings []string `json:"warnings,omitempty"` // data the output format cannot carry
+	Data     []byte   `json:"-"`                  // output bytes, for downloads
+}
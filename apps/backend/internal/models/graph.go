@@ -7,6 +7,7 @@ type GraphResponse struct {
 	Nodes []GraphNode `json:"nodes"`
 	Edges []GraphEdge `json:"edges"`
 	Chain ChainInfo   `json:"chain"`
+	Mode  string      `json:"mode,omitempty"` // principals or delegations
 }
 
 // GraphNode represents a principal in the graph
@@ -15,6 +16,9 @@ type GraphNode struct {
 	Label    string                 `json:"label"`
 	Type     string                 `json:"type"` // "root", "intermediate", "leaf"
 	Level    int                    `json:"level"`
+	Layer    int                    `json:"layer"` // row of the layered layout, from the top
+	X        float64                `json:"x"`     // top-left corner of the node's box
+	Y        float64                `json:"y"`
 	Metadata map[string]interface{} `json:"metadata"`
 }
 
@@ -28,6 +32,13 @@ type GraphEdge struct {
 	Level      int                    `json:"level"`
 	Type       string                 `json:"type"` // delegation, invocation, proof
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Points     []Point                `json:"points,omitempty"` // bends of edges spanning several layers
+}
+
+// Point is a position in the graph layout
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 // InvocationGraphResponse contains graph data for invocation visualization
 type InvocationGraphResponse struct {
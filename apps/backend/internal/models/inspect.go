@@ -0,0 +1,38 @@
+package models
+
+import "encoding/json"
+
+// CARInspection lists the blocks of a CAR as stored, whether or not they
+// parse as UCANs
+type CARInspection struct {
+	Version     uint64            `json:"version"`
+	Roots       []string          `json:"roots"`
+	Blocks      []BlockInfo       `json:"blocks"`
+	TotalSize   int               `json:"totalSize"`
+	Delegations []BlockDelegation `json:"delegations"`
+}
+
+// BlockInfo describes a single CAR block
+type BlockInfo struct {
+	CID          string          `json:"cid"`
+	Codec        string          `json:"codec"`     // multicodec name, e.g. "dag-cbor"
+	Multihash    string          `json:"multihash"` // hash function name, e.g. "sha2-256"
+	Size         int             `json:"size"`
+	Offset       uint64          `json:"offset"`
+	HashMatch    bool            `json:"hashMatch"` // the block bytes hash to its CID
+	IsRoot       bool            `json:"isRoot"`
+	Content      json.RawMessage `json:"content,omitempty"` // DAG-JSON rendering of the decoded block
+	DecodeError  string          `json:"decodeError,omitempty"`
+	Links        []string        `json:"links"`
+	ReferencedBy []string        `json:"referencedBy"` // delegations linking to this block
+}
+
+// BlockDelegation is a block that decodes as a delegation, with the blocks
+// it links to
+type BlockDelegation struct {
+	CID        string   `json:"cid"`
+	Issuer     string   `json:"issuer"`
+	Audience   string   `json:"audience"`
+	Proofs     []string `json:"proofs"`
+	References []string `json:"references"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// ReceiptResponse is a decoded ucanto receipt: the outcome of running an
+// invocation, signed by the executor
+type ReceiptResponse struct {
+	CID       string                 `json:"cid"`
+	Ran       RanInfo                `json:"ran"`
+	Out       ReceiptOutcome         `json:"out"`
+	Fx        ReceiptEffects         `json:"fx"`
+	Meta      map[string]interface{} `json:"meta"`
+	Issuer    string                 `json:"issuer"`
+	Proofs    []ProofInfo            `json:"proofs"`
+	Signature SignatureInfo          `json:"signature"`
+}
+
+// RanInfo identifies the invocation a receipt reports on
+type RanInfo struct {
+	CID        string              `json:"cid"`
+	Invocation *DelegationResponse `json:"invocation,omitempty"` // nil when the invocation is not in the archive
+}
+
+// ReceiptOutcome holds the ok or error branch of a receipt result
+type ReceiptOutcome struct {
+	Success bool        `json:"success"`
+	Ok      interface{} `json:"ok,omitempty"`
+	Error   interface{} `json:"error,omitempty"`
+}
+
+// ReceiptEffects lists the follow-up tasks a receipt schedules
+type ReceiptEffects struct {
+	Fork []string `json:"fork"`
+	Join string   `json:"join,omitempty"`
+}
+
+// ReceiptGraphResponse links a receipt to its invocation and the
+// delegation chain behind that invocation
+type ReceiptGraphResponse struct {
+	Nodes   []GraphNode      `json:"nodes"`
+	Edges   []GraphEdge      `json:"edges"`
+	Chain   ChainInfo        `json:"chain"`
+	Receipt *ReceiptResponse `json:"receipt"`
+}
@@ -20,9 +20,24 @@ type ParseRequest struct {
 type ValidateRequest struct {
 	Token  string `json:"token"`
 	Format string `json:"format,omitempty"`
+	// At evaluates the chain as of this RFC 3339 time instead of now
+	At *time.Time `json:"at,omitempty"`
+	// ExpiringSoon is a Go duration such as "72h" for the expiry warning window
+	ExpiringSoon string `json:"expiringSoon,omitempty"`
 }
 
 type GraphRequest struct {
 	Token  string `json:"token"`
 	Format string `json:"format,omitempty"`
+	// Export is dot, mermaid, graphml or cytoscape; the default is JSON
+	Export string `json:"export,omitempty"`
+	// Mode is principals (the default) or delegations
+	Mode string `json:"mode,omitempty"`
+}
+
+type RenderRequest struct {
+	Token  string `json:"token"`
+	Format string `json:"format,omitempty"`
+	// Image is svg or png; the default is SVG
+	Image string `json:"image,omitempty"`
 }
\ No newline at end of file
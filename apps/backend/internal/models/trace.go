@@ -0,0 +1,48 @@
+package models
+
+// TraceRequest asks how a principal comes to hold one capability
+type TraceRequest struct {
+	Token  string `json:"token"`
+	Format string `json:"format,omitempty"`
+	// Principal defaults to the audience of the token itself
+	Principal string `json:"principal,omitempty"`
+	Can       string `json:"can"`
+	// With matches any resource when empty
+	With string `json:"with,omitempty"`
+	// Export is dot, mermaid, graphml or cytoscape; the default is JSON
+	Export string `json:"export,omitempty"`
+}
+
+// CapabilityTrace follows one capability through a delegation chain to a
+// principal. Nodes and edges are the part of the principal graph carrying
+// the capability.
+type CapabilityTrace struct {
+	Principal string      `json:"principal"`
+	Can       string      `json:"can"`
+	With      string      `json:"with,omitempty"`
+	Granted   bool        `json:"granted"` // some path carries the capability intact
+	Reason    string      `json:"reason,omitempty"`
+	Break     *TraceHop   `json:"break,omitempty"` // first hop where the capability is lost, when not granted
+	Paths     []TracePath `json:"paths"`
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+}
+
+// TracePath is one route of proofs from where the capability originates
+// to a delegation issued to the principal
+type TracePath struct {
+	Granted bool       `json:"granted"`
+	Hops    []TraceHop `json:"hops"`
+}
+
+// TraceHop is one delegation along a trace path
+type TraceHop struct {
+	CID         string            `json:"cid"`
+	Issuer      string            `json:"issuer"`
+	Audience    string            `json:"audience"`
+	Level       int               `json:"level"`
+	Capability  *CapabilityInfo   `json:"capability,omitempty"`  // the hop's form of the capability
+	Status      string            `json:"status"`                // origin, unchanged, attenuated, broadened, dropped, absent, unproven
+	Attenuation *AttenuationCheck `json:"attenuation,omitempty"` // against the previous hop
+	Issue       string            `json:"issue,omitempty"`
+}
@@ -4,18 +4,59 @@ import "time"
 
 // Core delegation models
 type DelegationResponse struct {
-	Issuer       string              `json:"issuer"`
-	Audience     string              `json:"audience"`
-	Capabilities []CapabilityInfo    `json:"capabilities"`
-	Proofs       []ProofInfo         `json:"proofs"`
-	Expiration   time.Time           `json:"expiration,omitempty"`
-	NotBefore    time.Time           `json:"notBefore,omitempty"`
-	IssuedAt     time.Time           `json:"issuedAt,omitempty"`
-	Facts        []interface{}       `json:"facts,omitempty"`
-	Nonce        string              `json:"nonce,omitempty"`
-	Signature    SignatureInfo       `json:"signature"`
-	CID          string              `json:"cid"`
-	Level        int                 `json:"level"`
+	Issuer          string           `json:"issuer"`
+	Audience        string           `json:"audience"`
+	Capabilities    []CapabilityInfo `json:"capabilities"`
+	Proofs          []ProofInfo      `json:"proofs"`
+	Expiration      time.Time        `json:"expiration,omitempty"`
+	NotBefore       time.Time        `json:"notBefore,omitempty"`
+	IssuedAt        time.Time        `json:"issuedAt,omitempty"`
+	Facts           []interface{}    `json:"facts,omitempty"`
+	Nonce           string           `json:"nonce,omitempty"`
+	Signature       SignatureInfo    `json:"signature"`
+	CID             string           `json:"cid"`
+	Level           int              `json:"level"`
+	Envelope        *Envelope        `json:"envelope,omitempty"`        // UCAN 1.0 tokens only
+	Version         string           `json:"version,omitempty"`         // UCAN spec version: 0.8, 0.9, 0.10, 1.0-rc
+	VersionInferred bool             `json:"versionInferred,omitempty"` // no ucv: version guessed from the token's shape
+	Header          *JWTHeader       `json:"header,omitempty"`          // JWT tokens only
+	AttShape        string           `json:"attShape,omitempty"`        // JWT att: list (0.8, 0.9) or map (0.10)
+	Integrity       []BlockIntegrity `json:"integrity,omitempty"`       // blocks of this token that fail to hash to their CID
+}
+
+// JWTHeader is the header of a UCAN 0.x JWT
+type JWTHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Ucv string `json:"ucv,omitempty"` // 0.10 moves ucv into the payload
+}
+
+// Envelope holds the native fields of a UCAN 1.0 delegation or invocation.
+// 1.0 tokens carry a command and policy instead of capabilities, so
+// DelegationResponse.Capabilities stays empty for them.
+type Envelope struct {
+	Tag       string                 `json:"tag"`     // payload tag, e.g. ucan/dlg@1.0.0-rc.1
+	Kind      string                 `json:"kind"`    // delegation, invocation
+	Version   string                 `json:"version"` // e.g. 1.0.0-rc.1
+	Header    VarsigInfo             `json:"header"`
+	Command   string                 `json:"command"`
+	Subject   string                 `json:"subject,omitempty"`
+	Powerline bool                   `json:"powerline,omitempty"` // sub is null: valid for any subject
+	Policy    []interface{}          `json:"policy,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+	IssuedAt  time.Time              `json:"iat,omitzero"`
+	Cause     string                 `json:"cause,omitempty"`
+}
+
+// VarsigInfo is the decoded varsig header of a UCAN 1.0 envelope
+type VarsigInfo struct {
+	Raw             string `json:"raw"` // base64
+	Algorithm       string `json:"algorithm,omitempty"`
+	KeyType         string `json:"keyType,omitempty"`
+	Hash            string `json:"hash,omitempty"`
+	PayloadEncoding string `json:"payloadEncoding,omitempty"`
+	Error           string `json:"error,omitempty"`
 }
 
 // Enhanced capability model
@@ -28,9 +69,25 @@ type CapabilityInfo struct {
 
 // Enhanced proof model
 type ProofInfo struct {
-	CID   string `json:"cid"`
-	Index int    `json:"index"`
-	Type  string `json:"type"` // delegation, invocation, receipt
+	CID      string `json:"cid"`
+	Index    int    `json:"index"`
+	Type     string `json:"type"`             // delegation, invocation, receipt
+	Resolved bool   `json:"resolved"`         // proof block was available and decoded
+	Inline   bool   `json:"inline,omitempty"` // UCAN 0.8 proof carried as a JWT in prf
+}
+
+// BlockIntegrity is a CAR block whose bytes do not hash to its CID
+type BlockIntegrity struct {
+	CID      string `json:"cid"`      // CID the archive stores the block under
+	Computed string `json:"computed"` // CID the bytes actually hash to
+}
+
+// UnresolvedProof is a proof link whose delegation is missing from the token
+type UnresolvedProof struct {
+	CID     string `json:"cid"`
+	CitedBy string `json:"citedBy"` // CID of the delegation citing the proof
+	Index   int    `json:"index"`   // position in the citing delegation's proofs
+	Level   int    `json:"level"`   // level the proof would occupy in the chain
 }
 
 type SignatureInfo struct {
@@ -56,18 +113,21 @@ type TaskInfo struct {
 	Target      string                 `json:"target"`
 	TaskType    string                 `json:"taskType"` // invocation, delegation
 	Permissions []string               `json:"permissions"`
+	CID         string                 `json:"cid"`
+	ReceiptCID  string                 `json:"receiptCid,omitempty"` // receipt reporting on this task, if bundled
 }
 
 // Comprehensive invocation analysis
 type InvocationAnalysis struct {
 	IsInvocation        bool                   `json:"isInvocation"`
 	HasInvokeCapability bool                   `json:"hasInvokeCapability"`
-	TaskType           string                 `json:"taskType"` // invocation, delegation
-	PrimaryAction      string                 `json:"primaryAction"`
-	TargetResource     string                 `json:"targetResource"`
-	InvokePatterns     []string               `json:"invokePatterns"`
+	TaskType            string                 `json:"taskType"` // invocation, delegation
+	Source              string                 `json:"source"`   // agent_message, receipt, ucan_envelope, delegation
+	PrimaryAction       string                 `json:"primaryAction"`
+	TargetResource      string                 `json:"targetResource"`
+	InvokePatterns      []string               `json:"invokePatterns"`
 	RequiredPermissions []string               `json:"requiredPermissions"`
-	Constraints        map[string]interface{} `json:"constraints"`
+	Constraints         map[string]interface{} `json:"constraints"`
 }
 
 // Comprehensive capability analysis
@@ -81,6 +141,39 @@ type CapabilityAnalysis struct {
 }
 
 
+// DelegationDAG is the proof graph of a token. Each delegation appears once,
+// keyed by CID, and edges link a delegation to every proof it cites, so a
+// proof shared by several delegations forms a diamond rather than a copy.
+type DelegationDAG struct {
+	Root        string                         `json:"root"`
+	Delegations map[string]*DelegationResponse `json:"delegations"`
+	Order       []string                       `json:"order"` // CIDs breadth-first from the root
+	Edges       []ProofEdge                    `json:"edges"`
+	Unresolved  []UnresolvedProof              `json:"unresolved,omitempty"`
+}
+
+// ProofEdge links a delegation to a proof it cites
+type ProofEdge struct {
+	Parent   string `json:"parent"` // CID of the citing delegation
+	Proof    string `json:"proof"`  // CID of the cited proof
+	Index    int    `json:"index"`  // position in the parent's proofs
+	Resolved bool   `json:"resolved"`
+}
+
+// Ordered returns the delegations breadth-first from the root
+func (d *DelegationDAG) Ordered() []*DelegationResponse {
+	ordered := make([]*DelegationResponse, 0, len(d.Order))
+	for _, cid := range d.Order {
+		ordered = append(ordered, d.Delegations[cid])
+	}
+	return ordered
+}
+
+// RootDelegation returns the delegation the token was issued as
+func (d *DelegationDAG) RootDelegation() *DelegationResponse {
+	return d.Delegations[d.Root]
+}
+
 // Chain analysis models
 type ChainInfo struct {
 	TotalLevels int               `json:"totalLevels"`
@@ -90,6 +183,7 @@ type ChainInfo struct {
 	Principals  []PrincipalInfo   `json:"principals"`
 	Timeline    []TimelineEvent   `json:"timeline"`
 	ProofChain  *ProofChain       `json:"proofChain,omitempty"`
+	UnresolvedProofs []UnresolvedProof `json:"unresolvedProofs,omitempty"`
 }
 
 type ProofChain struct {
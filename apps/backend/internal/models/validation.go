@@ -4,33 +4,24 @@ import "time"
 
 // ValidationResult contains the complete validation outcome
 type ValidationResult struct {
-	Valid     bool              `json:"valid"`
-	Chain     []ChainLink       `json:"chain"`
-	RootCause *ValidationError  `json:"rootCause,omitempty"`
-	Summary   ValidationSummary `json:"summary"`
+	Valid          bool              `json:"valid"`
+	Chain          []ChainLink       `json:"chain"`
+	RootCause      *ValidationError  `json:"rootCause,omitempty"`
+	Summary        ValidationSummary `json:"summary"`
+	ValidityWindow *ValidityWindow   `json:"validityWindow,omitempty"`
+	EvaluatedAt    time.Time         `json:"evaluatedAt"`
+	Versions       []string          `json:"versions,omitempty"` // UCAN spec versions along the chain
+	MixedVersions  bool              `json:"mixedVersions"`
 }
 
-// ValidationChainResult for validating complete chains
-type ValidationChainResult struct {
-	Valid        bool                   `json:"valid"`
-	Chain        []ChainValidationLink  `json:"chain"`
-	RootCause    *ValidationError       `json:"rootCause,omitempty"`
-	Summary      ValidationSummary      `json:"summary"`
-	ChainInfo    ChainInfo              `json:"chainInfo"`
-}
-
-// ChainValidationLink represents validation of a single link in a chain
-type ChainValidationLink struct {
-	Level         int                 `json:"level"`
-	CID           string              `json:"cid"`
-	Issuer        string              `json:"issuer"`
-	Audience      string              `json:"audience"`
-	Capabilities  []CapabilityInfo    `json:"capabilities"`
-	Expiration    time.Time           `json:"expiration"`
-	NotBefore     time.Time           `json:"notBefore"`
-	Valid         bool                `json:"valid"`
-	Issues        []ValidationIssue   `json:"issues,omitempty"`
-	ProofValidation []ProofValidation `json:"proofValidation,omitempty"`
+// ValidityWindow is the intersection of the time bounds of every delegation
+// along the chain. Zero times mean the window is unbounded on that side.
+type ValidityWindow struct {
+	NotBefore     time.Time `json:"notBefore,omitzero"`
+	NotBeforeCID  string    `json:"notBeforeCid,omitempty"` // delegation that sets the lower bound
+	Expiration    time.Time `json:"expiration,omitzero"`
+	ExpirationCID string    `json:"expirationCid,omitempty"` // delegation that sets the upper bound
+	Empty         bool      `json:"empty"`
 }
 
 // ProofValidation represents validation of a specific proof
@@ -53,15 +44,19 @@ type AttenuationCheck struct {
 
 // ChainLink represents a single link in the validation chain
 type ChainLink struct {
-	Level      int              `json:"level"`
-	CID        string           `json:"cid"`
-	Issuer     string           `json:"issuer"`
-	Audience   string           `json:"audience"`
-	Capability CapabilityInfo   `json:"capability"`
-	Expiration time.Time        `json:"expiration"`
-	NotBefore  time.Time        `json:"notBefore"`
-	Valid      bool             `json:"valid"`
-	Issues     []ValidationIssue `json:"issues,omitempty"`
+	Level           int               `json:"level"`
+	CID             string            `json:"cid"`
+	Issuer          string            `json:"issuer"`
+	Audience        string            `json:"audience"`
+	Capability      CapabilityInfo    `json:"capability"`
+	Command         string            `json:"command,omitempty"` // UCAN 1.0 links
+	Subject         string            `json:"subject,omitempty"` // UCAN 1.0 links
+	Version         string            `json:"version,omitempty"`
+	Expiration      time.Time         `json:"expiration"`
+	NotBefore       time.Time         `json:"notBefore"`
+	Valid           bool              `json:"valid"`
+	Issues          []ValidationIssue `json:"issues,omitempty"`
+	ProofValidation []ProofValidation `json:"proofValidation,omitempty"`
 }
 
 // ValidationIssue represents a specific validation problem
@@ -81,6 +76,7 @@ type ValidationError struct {
 
 // LinkInfo contains minimal link information
 type LinkInfo struct {
+	CID      string `json:"cid,omitempty"`
 	Issuer   string `json:"issuer"`
 	Audience string `json:"audience"`
 }
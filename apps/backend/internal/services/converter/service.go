@@ -0,0 +1,496 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	_ "github.com/ipld/go-ipld-prime/codec/dagcbor"
+	_ "github.com/ipld/go-ipld-prime/codec/dagjson"
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	ipldmc "github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multicodec"
+	"github.com/storacha/go-ucanto/core/car"
+	ucantoipld "github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/ipld/block"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// Formats a token is converted between
+const (
+	FormatCAR       = "car"
+	FormatDAGJSON   = "dag-json"
+	FormatDAGCBOR   = "dag-cbor"
+	FormatJWT       = "jwt"
+	FormatBase64    = "base64"
+	FormatMultibase = "multibase"
+)
+
+// archiveVariant is the root block key under which ucanto archives link
+// the delegation they carry
+const archiveVariant = "ucan@0.9.1"
+
+// defaultMultibase is used when no base is given for multibase output
+const defaultMultibase = "base64"
+
+type Service struct{}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// token is a set of blocks and the roots they hang from, whatever format
+// they arrived in. A JWT is a raw block addressed the way UCAN 0.x proofs
+// cite it.
+type token struct {
+	from   string
+	roots  []cid.Cid
+	blocks []utils.CARBlock
+	native []byte // the token's own bytes; for a DAG-JSON node, its DAG-CBOR block
+}
+
+// Convert re-emits a CAR, DAG-JSON document, DAG-CBOR block or JWT in the
+// requested format. Every block keeps its CID: blocks that would not
+// re-encode to the same bytes are refused rather than silently rewritten,
+// and anything the target format cannot carry, such as the proofs of a
+// token converted to a single block, is listed in the warnings.
+func (s *Service) Convert(data []byte, to, base string) (*models.ConvertResponse, error) {
+	tok, err := readToken(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ConvertResponse{From: tok.from, To: strings.ToLower(to)}
+	switch result.To {
+	case FormatCAR:
+		err = tok.toCAR(result)
+	case FormatDAGJSON:
+		err = tok.toDAGJSON(result)
+	case FormatDAGCBOR:
+		err = tok.toDAGCBOR(result)
+	case FormatJWT:
+		err = tok.toJWT(result)
+	case FormatBase64, FormatMultibase:
+		err = tok.toText(result, base)
+	default:
+		return nil, fmt.Errorf("unsupported output format: %q", to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Encoding == FormatBase64 {
+		result.Output = base64.StdEncoding.EncodeToString(result.Data)
+	} else {
+		result.Output = string(result.Data)
+	}
+	return result, nil
+}
+
+// readToken recognizes the format of data and reads its blocks
+func readToken(data []byte) (*token, error) {
+	if err := utils.CheckCARv2(data); err != nil {
+		return nil, fmt.Errorf("invalid CAR: %w", err)
+	}
+	if file, err := utils.ReadCAR(data); err == nil {
+		for _, blk := range file.Blocks {
+			if err := utils.VerifyBlock(blk.CID, blk.Data); err != nil {
+				return nil, fmt.Errorf("cannot convert without changing CIDs: %w", err)
+			}
+		}
+		return &token{from: FormatCAR, roots: file.Roots, blocks: file.Blocks, native: data}, nil
+	}
+
+	text := strings.TrimSpace(string(data))
+	if fields := strings.Fields(text); len(fields) > 0 && allJWT(fields) {
+		return jwtToken(fields)
+	}
+
+	if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+		if node, err := decodeNode(cid.DagJSON, []byte(text)); err == nil {
+			return dagJSONToken(node)
+		}
+	}
+
+	if _, err := decodeNode(cid.DagCBOR, data); err == nil {
+		return blockToken(FormatDAGCBOR, data)
+	}
+
+	return nil, fmt.Errorf("unrecognized token: expected a CAR, DAG-JSON, DAG-CBOR block or JWT")
+}
+
+func allJWT(fields []string) bool {
+	for _, field := range fields {
+		if !utils.IsJWT(field) {
+			return false
+		}
+	}
+	return true
+}
+
+// jwtToken holds one or more whitespace-separated JWTs as raw blocks, the
+// first being the root
+func jwtToken(fields []string) (*token, error) {
+	tok := &token{from: FormatJWT, native: []byte(strings.Join(fields, "\n"))}
+	seen := make(map[cid.Cid]bool)
+	for _, field := range fields {
+		id, err := utils.TokenCID([]byte(field))
+		if err != nil {
+			return nil, err
+		}
+		if len(tok.roots) == 0 {
+			tok.roots = []cid.Cid{id}
+		}
+		if !seen[id] {
+			seen[id] = true
+			tok.blocks = append(tok.blocks, utils.CARBlock{CID: id, Data: []byte(field)})
+		}
+	}
+	return tok, nil
+}
+
+// blockToken holds a single DAG-CBOR block as its own root
+func blockToken(from string, data []byte) (*token, error) {
+	id, err := utils.TokenCID(data)
+	if err != nil {
+		return nil, err
+	}
+	return &token{
+		from:   from,
+		roots:  []cid.Cid{id},
+		blocks: []utils.CARBlock{{CID: id, Data: data}},
+		native: data,
+	}, nil
+}
+
+// dagJSONToken reads a DAG-JSON document as written by toDAGJSON, or a
+// bare node, which stands for the DAG-CBOR block it encodes to
+func dagJSONToken(node ipld.Node) (*token, error) {
+	roots, rootsErr := node.LookupByString("roots")
+	blocks, blocksErr := node.LookupByString("blocks")
+	if rootsErr != nil || blocksErr != nil {
+		data, err := encodeNode(cid.DagCBOR, node)
+		if err != nil {
+			return nil, err
+		}
+		return blockToken(FormatDAGJSON, data)
+	}
+
+	tok := &token{from: FormatDAGJSON}
+	for it := roots.ListIterator(); it != nil && !it.Done(); {
+		_, item, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		id, err := nodeCID(item)
+		if err != nil {
+			return nil, fmt.Errorf("root: %w", err)
+		}
+		tok.roots = append(tok.roots, id)
+	}
+
+	for it := blocks.ListIterator(); it != nil && !it.Done(); {
+		i, item, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		link, err := item.LookupByString("cid")
+		if err != nil {
+			return nil, fmt.Errorf("block %d: missing cid", i)
+		}
+		id, err := nodeCID(link)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		content, err := item.LookupByString("data")
+		if err != nil {
+			return nil, fmt.Errorf("block %s: missing data", id)
+		}
+		data, err := encodeNode(id.Prefix().Codec, content)
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %w", id, err)
+		}
+		if err := utils.VerifyBlock(id, data); err != nil {
+			return nil, fmt.Errorf("block %s does not re-encode to its CID: %w", id, err)
+		}
+		tok.blocks = append(tok.blocks, utils.CARBlock{CID: id, Data: data})
+	}
+
+	if len(tok.roots) == 0 {
+		return nil, fmt.Errorf("DAG-JSON document has no roots")
+	}
+	return tok, nil
+}
+
+// toCAR writes the token as a CARv1. A CAR is returned as it came, so a
+// CARv2 stays one.
+func (t *token) toCAR(result *models.ConvertResponse) error {
+	result.Encoding = FormatBase64
+	result.Roots = cidStrings(t.roots)
+	result.Blocks = len(t.blocks)
+
+	if t.from == FormatCAR {
+		result.Data = t.native
+		return nil
+	}
+
+	data, err := t.encodeCAR()
+	if err != nil {
+		return err
+	}
+	result.Data = data
+	return nil
+}
+
+// toDAGJSON writes every block into one DAG-JSON document:
+//
+//	{"roots": [{"/": cid}], "blocks": [{"cid": {"/": cid}, "data": block}]}
+//
+// Raw blocks, such as JWTs, appear as DAG-JSON bytes. A block that does not
+// decode and re-encode to the same bytes, as non-canonical DAG-CBOR would
+// not, is refused since its CID would change on the way back.
+func (t *token) toDAGJSON(result *models.ConvertResponse) error {
+	contents := make([]ipld.Node, 0, len(t.blocks))
+	for _, blk := range t.blocks {
+		codec := blk.CID.Prefix().Codec
+		node, err := decodeNode(codec, blk.Data)
+		if err != nil {
+			return fmt.Errorf("block %s: %w", blk.CID, err)
+		}
+		encoded, err := encodeNode(codec, node)
+		if err != nil || !bytes.Equal(encoded, blk.Data) {
+			return fmt.Errorf("block %s is not canonical %s and would change CID as DAG-JSON; convert to car instead",
+				blk.CID, multicodec.Code(codec))
+		}
+		contents = append(contents, node)
+	}
+
+	doc, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma ipld.MapAssembler) {
+		qp.MapEntry(ma, "roots", qp.List(int64(len(t.roots)), func(la ipld.ListAssembler) {
+			for _, root := range t.roots {
+				qp.ListEntry(la, qp.Link(cidlink.Link{Cid: root}))
+			}
+		}))
+		qp.MapEntry(ma, "blocks", qp.List(int64(len(t.blocks)), func(la ipld.ListAssembler) {
+			for i, blk := range t.blocks {
+				qp.ListEntry(la, qp.Map(2, func(ma ipld.MapAssembler) {
+					qp.MapEntry(ma, "cid", qp.Link(cidlink.Link{Cid: blk.CID}))
+					qp.MapEntry(ma, "data", qp.Node(contents[i]))
+				}))
+			}
+		}))
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeNode(cid.DagJSON, doc)
+	if err != nil {
+		return err
+	}
+	result.Data = data
+	result.Roots = cidStrings(t.roots)
+	result.Blocks = len(t.blocks)
+	return nil
+}
+
+// toDAGCBOR writes the root token as a single DAG-CBOR block. The root of
+// a ucanto archive is followed to the delegation it links.
+func (t *token) toDAGCBOR(result *models.ConvertResponse) error {
+	blk, err := t.rootBlock()
+	if err != nil {
+		return err
+	}
+	if codec := blk.CID.Prefix().Codec; codec != cid.DagCBOR {
+		return fmt.Errorf("root block %s is %s, not DAG-CBOR", blk.CID, multicodec.Code(codec))
+	}
+
+	result.Data = blk.Data
+	result.Encoding = FormatBase64
+	result.Roots = []string{blk.CID.String()}
+	result.Blocks = 1
+	if omitted := len(t.blocks) - 1; omitted > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"%d other blocks, such as proofs, do not fit in a single DAG-CBOR block; convert to car to keep them", omitted))
+	}
+	return nil
+}
+
+// toJWT recovers the JWT a token was built from, followed by any other
+// JWTs it carries, one per line, as /api/parse accepts them
+func (t *token) toJWT(result *models.ConvertResponse) error {
+	root, err := t.rootBlock()
+	if err != nil {
+		return err
+	}
+	if root.CID.Prefix().Codec != cid.Raw || !utils.IsJWT(string(root.Data)) {
+		return fmt.Errorf("token is not JWT-origin: root block %s is %s",
+			root.CID, multicodec.Code(root.CID.Prefix().Codec))
+	}
+
+	jwts := []string{string(root.Data)}
+	var omitted int
+	for _, blk := range t.blocks {
+		switch {
+		case blk.CID.Equals(root.CID):
+		case blk.CID.Prefix().Codec == cid.Raw && utils.IsJWT(string(blk.Data)):
+			jwts = append(jwts, string(blk.Data))
+		default:
+			omitted++
+		}
+	}
+
+	result.Data = []byte(strings.Join(jwts, "\n"))
+	result.Roots = []string{root.CID.String()}
+	result.Blocks = len(jwts)
+	if omitted > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"%d blocks are not JWTs and were left out; convert to car to keep them", omitted))
+	}
+	return nil
+}
+
+// toText encodes the token's own bytes, or a CAR of anything read from
+// DAG-JSON, as base64 or multibase text
+func (t *token) toText(result *models.ConvertResponse, base string) error {
+	data := t.native
+	if t.from == FormatDAGJSON {
+		var err error
+		if data, err = t.encodeCAR(); err != nil {
+			return err
+		}
+	}
+
+	if result.To == FormatBase64 {
+		result.Data = []byte(base64.StdEncoding.EncodeToString(data))
+	} else {
+		if base == "" {
+			base = defaultMultibase
+		}
+		encoder, err := multibase.EncoderByName(base)
+		if err != nil {
+			return err
+		}
+		result.Data = []byte(encoder.Encode(data))
+	}
+	result.Roots = cidStrings(t.roots)
+	result.Blocks = len(t.blocks)
+	return nil
+}
+
+// rootBlock returns the block of the token's only root, following the
+// variant block of a ucanto archive to the delegation it links
+func (t *token) rootBlock() (utils.CARBlock, error) {
+	if len(t.roots) != 1 {
+		return utils.CARBlock{}, fmt.Errorf("token has %d roots; only a single-root token converts to one block", len(t.roots))
+	}
+
+	blk, ok := t.block(t.roots[0])
+	if !ok {
+		return utils.CARBlock{}, fmt.Errorf("root block %s is missing", t.roots[0])
+	}
+	if blk.CID.Prefix().Codec != cid.DagCBOR {
+		return blk, nil
+	}
+
+	node, err := decodeNode(cid.DagCBOR, blk.Data)
+	if err != nil || node.Kind() != ipld.Kind_Map {
+		return blk, nil
+	}
+	variant, err := node.LookupByString(archiveVariant)
+	if err != nil {
+		return blk, nil
+	}
+	link, err := nodeCID(variant)
+	if err != nil {
+		return blk, nil
+	}
+	if linked, ok := t.block(link); ok {
+		return linked, nil
+	}
+	return blk, nil
+}
+
+func (t *token) block(id cid.Cid) (utils.CARBlock, bool) {
+	for _, blk := range t.blocks {
+		if blk.CID.Equals(id) {
+			return blk, true
+		}
+	}
+	return utils.CARBlock{}, false
+}
+
+// encodeCAR writes the blocks under their roots as a CARv1
+func (t *token) encodeCAR() ([]byte, error) {
+	roots := make([]ucantoipld.Link, 0, len(t.roots))
+	for _, root := range t.roots {
+		roots = append(roots, cidlink.Link{Cid: root})
+	}
+	return io.ReadAll(car.Encode(roots, func(yield func(ucantoipld.Block, error) bool) {
+		for _, blk := range t.blocks {
+			if !yield(block.NewBlock(cidlink.Link{Cid: blk.CID}, blk.Data), nil) {
+				return
+			}
+		}
+	}))
+}
+
+// decodeNode decodes data with the codec registered for code
+func decodeNode(code uint64, data []byte) (ipld.Node, error) {
+	decode, err := ipldmc.LookupDecoder(code)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported codec %s", multicodec.Code(code))
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	r := bytes.NewReader(data)
+	if err := decode(nb, r); err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after %s data", r.Len(), multicodec.Code(code))
+	}
+	return nb.Build(), nil
+}
+
+// encodeNode encodes node with the codec registered for code
+func encodeNode(code uint64, node ipld.Node) ([]byte, error) {
+	encode, err := ipldmc.LookupEncoder(code)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported codec %s", multicodec.Code(code))
+	}
+	var buf bytes.Buffer
+	if err := encode(node, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func nodeCID(node ipld.Node) (cid.Cid, error) {
+	link, err := node.AsLink()
+	if err != nil {
+		return cid.Undef, err
+	}
+	cl, ok := link.(cidlink.Link)
+	if !ok {
+		return cid.Undef, fmt.Errorf("unsupported link %s", link)
+	}
+	return cl.Cid, nil
+}
+
+func cidStrings(ids []cid.Cid) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.String())
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+)
+
+// GenerateBundleGraph graphs each chain of a bundle on its own and merges
+// the graphs into one
+func (s *Service) GenerateBundleGraph(bundle *models.Bundle) ([]*models.GraphResponse, models.CombinedGraph) {
+	graphs := make([]*models.GraphResponse, 0, len(bundle.Chains))
+	for _, chain := range bundle.Chains {
+		graphs = append(graphs, s.GenerateDAGGraph(chain.DAG))
+	}
+	return graphs, mergeGraphs(bundle.Chains, graphs)
+}
+
+// mergeGraphs combines per-root graphs. Nodes are merged by ID, so a
+// principal delegating under several roots becomes one node, placed at its
+// shallowest level; edges shared by several chains, such as those of a
+// common proof, appear once. Both list the root delegations they belong to
+// in metadata.roots.
+func mergeGraphs(chains []models.BundleChain, graphs []*models.GraphResponse) models.CombinedGraph {
+	combined := models.CombinedGraph{
+		Nodes:            []models.GraphNode{},
+		Edges:            []models.GraphEdge{},
+		SharedPrincipals: []string{},
+	}
+	nodeIndex := make(map[string]int)
+	edgeIndex := make(map[string]int)
+
+	for i, graph := range graphs {
+		root := chains[i].DAG.Root
+
+		for _, node := range graph.Nodes {
+			j, seen := nodeIndex[node.ID]
+			if !seen {
+				node.Metadata = withRoot(node.Metadata, root)
+				nodeIndex[node.ID] = len(combined.Nodes)
+				combined.Nodes = append(combined.Nodes, node)
+				continue
+			}
+
+			merged := &combined.Nodes[j]
+			addRoot(merged.Metadata, root)
+			if node.Level < merged.Level {
+				merged.Level = node.Level
+				merged.Type = node.Type
+			}
+		}
+
+		for _, edge := range graph.Edges {
+			key := edgeKey(edge)
+			if j, seen := edgeIndex[key]; seen {
+				addRoot(combined.Edges[j].Metadata, root)
+				continue
+			}
+			edge.Metadata = withRoot(edge.Metadata, root)
+			edgeIndex[key] = len(combined.Edges)
+			combined.Edges = append(combined.Edges, edge)
+		}
+	}
+
+	// The per-root layouts no longer fit, so lay the whole bundle out again
+	combined.Nodes = applyLayout(combined.Nodes, combined.Edges)
+
+	for i, node := range combined.Nodes {
+		roots, _ := node.Metadata["roots"].([]string)
+		shared := len(roots) > 1
+		combined.Nodes[i].Metadata["shared"] = shared
+		if shared && node.Type != "unresolved" {
+			combined.SharedPrincipals = append(combined.SharedPrincipals, node.ID)
+		}
+	}
+
+	return combined
+}
+
+// withRoot copies metadata, which the per-root graph still holds, and
+// starts its list of roots
+func withRoot(metadata map[string]interface{}, root string) map[string]interface{} {
+	copied := make(map[string]interface{}, len(metadata)+1)
+	maps.Copy(copied, metadata)
+	copied["roots"] = []string{root}
+	return copied
+}
+
+func addRoot(metadata map[string]interface{}, root string) {
+	roots, _ := metadata["roots"].([]string)
+	if !slices.Contains(roots, root) {
+		metadata["roots"] = append(roots, root)
+	}
+}
+
+// edgeKey identifies an edge across graphs: the same delegation or proof
+// citation yields the same key under every root
+func edgeKey(edge models.GraphEdge) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%v|%v",
+		edge.Type, edge.Source, edge.Target, edge.Label, edge.Metadata["cid"], edge.Metadata["parentCID"])
+}
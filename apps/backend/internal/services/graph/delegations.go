@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/validator"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// Graph modes: principals as nodes with capabilities as edges, or
+// delegations as nodes with the proofs between them as edges
+const (
+	ModePrincipals  = "principals"
+	ModeDelegations = "delegations"
+)
+
+// GenerateProofGraph creates the delegation-centric view of a chain: one
+// node per delegation, and a "proves" edge from each proof to every
+// delegation citing it
+func (s *Service) GenerateProofGraph(tokenBytes []byte) (*models.GraphResponse, error) {
+	dag, err := s.parser.ParseDelegationChain(tokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse delegation chain: %w", err)
+	}
+
+	return s.GenerateDAGProofGraph(dag), nil
+}
+
+// GenerateDAGProofGraph creates the delegation-centric view of an already
+// parsed DAG, validated as of now
+func (s *Service) GenerateDAGProofGraph(dag *models.DelegationDAG) *models.GraphResponse {
+	validation := s.validator.ValidateDAG(dag, validator.Options{})
+	nodes, edges := s.buildProofGraph(dag, validation)
+
+	return &models.GraphResponse{
+		Nodes: applyLayout(nodes, edges),
+		Edges: edges,
+		Chain: s.buildChainInfo(dag),
+		Mode:  ModeDelegations,
+	}
+}
+
+// buildProofGraph creates a node for every delegation, typed by its place
+// in the proof DAG, and an edge for every proof citation. A cited proof
+// missing from the token gets an unresolved node under its own CID, so
+// every edge joins two nodes.
+func (s *Service) buildProofGraph(dag *models.DelegationDAG, validation *models.ValidationResult) ([]models.GraphNode, []models.GraphEdge) {
+	var nodes []models.GraphNode
+	var edges []models.GraphEdge
+
+	links := chainLinks(validation)
+
+	// Delegations resting on a resolved proof are not where authority starts
+	cited := make(map[string]bool)
+	for _, edge := range dag.Edges {
+		if edge.Resolved {
+			cited[edge.Parent] = true
+		}
+	}
+
+	for _, del := range dag.Ordered() {
+		link := links[del.CID]
+
+		nodeType := "intermediate"
+		switch {
+		case del.CID == dag.Root:
+			nodeType = "root"
+		case !cited[del.CID]:
+			nodeType = "leaf"
+		}
+
+		metadata := map[string]interface{}{
+			"cid":            del.CID,
+			"issuer":         del.Issuer,
+			"audience":       del.Audience,
+			"capabilities":   del.Capabilities,
+			"proofs":         len(del.Proofs),
+			"version":        del.Version,
+			"signatureValid": del.Signature.Valid,
+			"valid":          link.Valid,
+			"issues":         link.Issues,
+		}
+		if !del.NotBefore.IsZero() {
+			metadata["notBefore"] = del.NotBefore
+		}
+		if !del.Expiration.IsZero() {
+			metadata["expiration"] = del.Expiration
+		}
+		if env := del.Envelope; env != nil {
+			metadata["command"] = env.Command
+			metadata["subject"] = env.Subject
+			metadata["powerline"] = env.Powerline
+		}
+
+		nodes = append(nodes, models.GraphNode{
+			ID:       del.CID,
+			Label:    utils.ShortenDID(del.CID),
+			Type:     nodeType,
+			Level:    del.Level,
+			Metadata: metadata,
+		})
+	}
+
+	missing := make(map[string]bool)
+	for _, proofEdge := range dag.Edges {
+		del := dag.Delegations[proofEdge.Parent]
+		proof := del.Proofs[proofEdge.Index]
+
+		if !proofEdge.Resolved && !missing[proofEdge.Proof] {
+			missing[proofEdge.Proof] = true
+			nodes = append(nodes, models.GraphNode{
+				ID:    proofEdge.Proof,
+				Label: fmt.Sprintf("Missing proof %s", utils.ShortenDID(proofEdge.Proof)),
+				Type:  "unresolved",
+				Level: del.Level + 1,
+				Metadata: map[string]interface{}{
+					"cid":     proofEdge.Proof,
+					"citedBy": proofEdge.Parent,
+					"index":   proofEdge.Index,
+				},
+			})
+		}
+
+		metadata := map[string]interface{}{
+			"proofCID":  proofEdge.Proof,
+			"proofType": proof.Type,
+			"parentCID": proofEdge.Parent,
+			"index":     proofEdge.Index,
+			"resolved":  proofEdge.Resolved,
+		}
+
+		// A proof need not cover everything the citing delegation claims
+		// when other proofs cover the rest, so the edge holds when the proof
+		// resolves and is itself valid. Whether it covers the citing
+		// delegation shows in its attenuation check and on the parent node.
+		valid := proofEdge.Resolved && links[proofEdge.Proof].Valid
+		for _, check := range links[proofEdge.Parent].ProofValidation {
+			if check.ProofCID == proofEdge.Proof {
+				metadata["attenuation"] = check.Attenuation
+			}
+		}
+
+		edges = append(edges, models.GraphEdge{
+			Source:   proofEdge.Proof,
+			Target:   proofEdge.Parent,
+			Label:    fmt.Sprintf("Proof %d", proofEdge.Index),
+			Valid:    valid,
+			Level:    del.Level + 1,
+			Type:     "proves",
+			Metadata: metadata,
+		})
+	}
+
+	return nodes, edges
+}
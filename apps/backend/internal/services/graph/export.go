@@ -0,0 +1,340 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+)
+
+// Graph export formats
+const (
+	ExportJSON      = "json"
+	ExportDOT       = "dot"
+	ExportMermaid   = "mermaid"
+	ExportGraphML   = "graphml"
+	ExportCytoscape = "cytoscape"
+)
+
+// ExportMediaTypes maps each export format to the media type it is served
+// as, which an Accept header may also ask for
+var ExportMediaTypes = map[string]string{
+	ExportJSON:      "application/json",
+	ExportDOT:       "text/vnd.graphviz",
+	ExportMermaid:   "text/vnd.mermaid",
+	ExportGraphML:   "application/graphml+xml",
+	ExportCytoscape: "application/vnd.cytoscape+json",
+}
+
+// Export writes the nodes and edges of a graph as Graphviz DOT, a Mermaid
+// flowchart, GraphML or Cytoscape.js JSON. Node types, capability labels
+// and edge validity carry over as attributes and as the colours the
+// frontend draws them in.
+func (s *Service) Export(format string, nodes []models.GraphNode, edges []models.GraphEdge) ([]byte, error) {
+	switch format {
+	case ExportDOT:
+		return exportDOT(nodes, edges), nil
+	case ExportMermaid:
+		return exportMermaid(nodes, edges), nil
+	case ExportGraphML:
+		return exportGraphML(nodes, edges)
+	case ExportCytoscape:
+		return exportCytoscape(nodes, edges)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %q", format)
+	}
+}
+
+// exportDOT writes a Graphviz digraph. Nodes carry their type as a second
+// label line and as the SVG class.
+func exportDOT(nodes []models.GraphNode, edges []models.GraphEdge) []byte {
+	var b strings.Builder
+	b.WriteString("digraph delegation {\n")
+	b.WriteString("  rankdir=TB;\n")
+	fmt.Fprintf(&b, "  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\", fontcolor=%s, color=%s];\n",
+		dotQuote(colorNodeText), dotQuote(colorAccentDark))
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, node := range nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, tooltip=%s, fillcolor=%s, class=%s];\n",
+			dotQuote(node.ID),
+			dotQuote(node.Label+"\n"+node.Type),
+			dotQuote(node.ID),
+			dotQuote(nodeColor(node)),
+			dotQuote(node.Type))
+	}
+
+	for _, edge := range edges {
+		style := "solid"
+		if edgeDashed(edge) {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, color=%s, fontcolor=%s, style=%s, class=%s];\n",
+			dotQuote(edge.Source),
+			dotQuote(edge.Target),
+			dotQuote(edge.Label),
+			dotQuote(edgeColor(edge)),
+			dotQuote(edgeColor(edge)),
+			style,
+			dotQuote(edgeClass(edge)))
+	}
+
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// dotQuote quotes s as a DOT string, keeping newlines as line breaks
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// exportMermaid writes a top-down flowchart. Node IDs are replaced by n0,
+// n1, ... as DIDs are not valid Mermaid identifiers; node types become
+// classes and edge colours link styles.
+func exportMermaid(nodes []models.GraphNode, edges []models.GraphEdge) []byte {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	ids := make(map[string]string, len(nodes))
+	types := make(map[string]bool)
+	var classes []string
+	for i, node := range nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[node.ID] = id
+		fmt.Fprintf(&b, "  %s[\"%s<br/><i>%s</i>\"]:::%s\n",
+			id, mermaidEscape(node.Label), mermaidEscape(node.Type), mermaidClass(node.Type))
+		if !types[node.Type] {
+			types[node.Type] = true
+			classes = append(classes, fmt.Sprintf("  classDef %s fill:%s,stroke:%s,color:%s\n",
+				mermaidClass(node.Type), nodeColor(node), colorAccentDark, colorNodeText))
+		}
+	}
+
+	var styles []string
+	for i, edge := range edges {
+		arrow := "-->"
+		if edgeDashed(edge) {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|\"%s\"| %s\n", ids[edge.Source], arrow, mermaidEscape(edge.Label), ids[edge.Target])
+		styles = append(styles, fmt.Sprintf("  linkStyle %d stroke:%s,color:%s\n", i, edgeColor(edge), edgeColor(edge)))
+	}
+
+	for _, class := range classes {
+		b.WriteString(class)
+	}
+	for _, style := range styles {
+		b.WriteString(style)
+	}
+	return []byte(b.String())
+}
+
+// mermaidEscape makes s safe inside a quoted Mermaid label
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", "<br/>").Replace(s)
+}
+
+// mermaidClass turns a node type into a class name, which must not clash
+// with Mermaid keywords such as "end"
+func mermaidClass(nodeType string) string {
+	return "ucan_" + strings.ReplaceAll(nodeType, "-", "_")
+}
+
+// GraphML document, declaring every attribute as a key
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{ID: "n_label", For: "node", Name: "label", Type: "string"},
+	{ID: "n_type", For: "node", Name: "type", Type: "string"},
+	{ID: "n_level", For: "node", Name: "level", Type: "int"},
+	{ID: "n_color", For: "node", Name: "color", Type: "string"},
+	{ID: "e_label", For: "edge", Name: "label", Type: "string"},
+	{ID: "e_type", For: "edge", Name: "type", Type: "string"},
+	{ID: "e_capability", For: "edge", Name: "capability", Type: "string"},
+	{ID: "e_resource", For: "edge", Name: "resource", Type: "string"},
+	{ID: "e_cid", For: "edge", Name: "cid", Type: "string"},
+	{ID: "e_valid", For: "edge", Name: "valid", Type: "boolean"},
+	{ID: "e_color", For: "edge", Name: "color", Type: "string"},
+}
+
+// exportGraphML writes a directed GraphML graph
+func exportGraphML(nodes []models.GraphNode, edges []models.GraphEdge) ([]byte, error) {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: "delegation", EdgeDefault: "directed"},
+	}
+
+	for _, node := range nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "n_label", Value: node.Label},
+				{Key: "n_type", Value: node.Type},
+				{Key: "n_level", Value: fmt.Sprint(node.Level)},
+				{Key: "n_color", Value: nodeColor(node)},
+			},
+		})
+	}
+
+	for i, edge := range edges {
+		data := []graphMLData{
+			{Key: "e_label", Value: edge.Label},
+			{Key: "e_type", Value: edge.Type},
+		}
+		if edge.Capability.Can != "" {
+			data = append(data,
+				graphMLData{Key: "e_capability", Value: edge.Capability.Can},
+				graphMLData{Key: "e_resource", Value: edge.Capability.With})
+		}
+		if cid, ok := edge.Metadata["cid"].(string); ok {
+			data = append(data, graphMLData{Key: "e_cid", Value: cid})
+		}
+		data = append(data,
+			graphMLData{Key: "e_valid", Value: fmt.Sprint(edge.Valid)},
+			graphMLData{Key: "e_color", Value: edgeColor(edge)})
+
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: edge.Source,
+			Target: edge.Target,
+			Data:   data,
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// exportCytoscape writes Cytoscape.js elements together with a stylesheet
+// reading each element's colour from its data, so cy.json() or
+// cytoscape({elements, style}) draws it as the frontend does
+func exportCytoscape(nodes []models.GraphNode, edges []models.GraphEdge) ([]byte, error) {
+	type element struct {
+		Data    map[string]interface{} `json:"data"`
+		Classes string                 `json:"classes,omitempty"`
+	}
+
+	elements := struct {
+		Nodes []element `json:"nodes"`
+		Edges []element `json:"edges"`
+	}{Nodes: []element{}, Edges: []element{}}
+
+	for _, node := range nodes {
+		elements.Nodes = append(elements.Nodes, element{
+			Data: map[string]interface{}{
+				"id":       node.ID,
+				"label":    node.Label,
+				"type":     node.Type,
+				"level":    node.Level,
+				"color":    nodeColor(node),
+				"metadata": node.Metadata,
+			},
+			Classes: node.Type,
+		})
+	}
+
+	for i, edge := range edges {
+		data := map[string]interface{}{
+			"id":       fmt.Sprintf("e%d", i),
+			"source":   edge.Source,
+			"target":   edge.Target,
+			"label":    edge.Label,
+			"type":     edge.Type,
+			"valid":    edge.Valid,
+			"color":    edgeColor(edge),
+			"metadata": edge.Metadata,
+		}
+		if edge.Capability.Can != "" {
+			data["capability"] = edge.Capability.Can
+			data["resource"] = edge.Capability.With
+		}
+		elements.Edges = append(elements.Edges, element{Data: data, Classes: edgeClass(edge)})
+	}
+
+	style := []map[string]interface{}{
+		{"selector": "node", "style": map[string]interface{}{
+			"background-color": "data(color)",
+			"label":            "data(label)",
+			"color":            colorNodeText,
+			"text-valign":      "center",
+			"shape":            "round-rectangle",
+		}},
+		{"selector": "edge", "style": map[string]interface{}{
+			"line-color":         "data(color)",
+			"target-arrow-color": "data(color)",
+			"target-arrow-shape": "triangle",
+			"curve-style":        "bezier",
+			"label":              "data(label)",
+		}},
+		{"selector": "edge.dashed", "style": map[string]interface{}{
+			"line-style": "dashed",
+		}},
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"elements": elements,
+		"style":    style,
+	}, "", "  ")
+}
+
+// edgeClass lists the edge type, its validity and whether it is dashed
+func edgeClass(edge models.GraphEdge) string {
+	classes := []string{edge.Type, "valid"}
+	if !edge.Valid {
+		classes[1] = "invalid"
+	}
+	if edgeDashed(edge) {
+		classes = append(classes, "dashed")
+	}
+	return strings.Join(classes, " ")
+}
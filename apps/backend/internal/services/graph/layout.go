@@ -0,0 +1,482 @@
+package graph
+
+import (
+	"math"
+	"sort"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+)
+
+// Layout geometry, in SVG user units
+const (
+	nodeWidth  = 240
+	nodeHeight = 64
+	nodeGap    = 60  // between nodes of a layer
+	layerGap   = 120 // between the bottom of a layer and the top of the next
+	margin     = 40
+
+	dummyWidth = 20 // room kept for an edge passing through a layer
+	dummyGap   = 20 // between an edge and its neighbours on a layer
+)
+
+// Iteration limits of the layout heuristics
+const (
+	orderingSweeps   = 24
+	coordinatePasses = 8
+)
+
+// position is the top-left corner of a node's box
+type position struct {
+	X, Y float64
+}
+
+// graphLayout is the result of laying a graph out
+type graphLayout struct {
+	positions     map[string]position // top-left corner of each node
+	layers        map[string]int
+	order         []string        // node IDs layer by layer, left to right
+	bends         map[int][]point // by edge index, from source to target
+	width, height float64
+}
+
+// vertex is a node, or a dummy marking where an edge crosses a layer it
+// passes through
+type vertex struct {
+	id    string // empty for dummies
+	layer int
+	width float64
+	up    []int // neighbours on the layer above
+	down  []int // neighbours on the layer below
+}
+
+// applyLayout lays the graph out and records the result on it: nodes get
+// their layer and the top-left corner of their box, and edges spanning
+// several layers get the bend points of their route. Nodes are returned
+// layer by layer, left to right, so their order is the same on every
+// request for the same token.
+func applyLayout(nodes []models.GraphNode, edges []models.GraphEdge) []models.GraphNode {
+	l := layeredLayout(nodes, edges)
+
+	rank := make(map[string]int, len(l.order))
+	for i, id := range l.order {
+		rank[id] = i
+	}
+	sorted := make([]models.GraphNode, len(nodes))
+	copy(sorted, nodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank[sorted[i].ID] < rank[sorted[j].ID]
+	})
+	for i := range sorted {
+		pos := l.positions[sorted[i].ID]
+		sorted[i].Layer = l.layers[sorted[i].ID]
+		sorted[i].X = pos.X
+		sorted[i].Y = pos.Y
+	}
+
+	for i := range edges {
+		edges[i].Points = nil
+		for _, p := range l.bends[i] {
+			edges[i].Points = append(edges[i].Points, models.Point{X: p.X, Y: p.Y})
+		}
+	}
+
+	return sorted
+}
+
+// layeredLayout places nodes on horizontal layers in the Sugiyama style:
+// edges closing a cycle are reversed, nodes are layered by longest path so
+// that edges point down, edges spanning several layers are split by dummy
+// vertices, layers are reordered by barycentre to reduce crossings, and x
+// coordinates pull every vertex towards its neighbours. Nodes keep the
+// order they are given in wherever the heuristics tie, so equal graphs get
+// equal layouts.
+func layeredLayout(nodes []models.GraphNode, edges []models.GraphEdge) *graphLayout {
+	var ids []string
+	known := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if !known[node.ID] {
+			known[node.ID] = true
+			ids = append(ids, node.ID)
+		}
+	}
+
+	succ := acyclicSuccessors(ids, edges)
+	layer := assignLayers(ids, succ)
+
+	// Split every edge into segments between adjacent layers
+	vs := make([]vertex, 0, len(ids))
+	index := make(map[string]int, len(ids))
+	for _, id := range ids {
+		index[id] = len(vs)
+		vs = append(vs, vertex{id: id, layer: layer[id], width: nodeWidth})
+	}
+	chains := make(map[[2]string][]int)
+	for _, id := range ids {
+		for _, next := range succ[id] {
+			key := [2]string{id, next}
+			if _, exists := chains[key]; exists {
+				continue
+			}
+			chain := []int{index[id]}
+			for l := layer[id] + 1; l < layer[next]; l++ {
+				chain = append(chain, len(vs))
+				vs = append(vs, vertex{layer: l, width: dummyWidth})
+			}
+			chain = append(chain, index[next])
+			for i := 1; i < len(chain); i++ {
+				vs[chain[i-1]].down = append(vs[chain[i-1]].down, chain[i])
+				vs[chain[i]].up = append(vs[chain[i]].up, chain[i-1])
+			}
+			chains[key] = chain
+		}
+	}
+
+	var layers [][]int
+	for v := range vs {
+		for len(layers) <= vs[v].layer {
+			layers = append(layers, nil)
+		}
+		layers[vs[v].layer] = append(layers[vs[v].layer], v)
+	}
+
+	layers = orderLayers(layers, vs)
+	x := assignCoordinates(layers, vs)
+
+	// Shift everything right of the margin
+	left, right := math.Inf(1), math.Inf(-1)
+	for v := range vs {
+		left = math.Min(left, x[v]-vs[v].width/2)
+		right = math.Max(right, x[v]+vs[v].width/2)
+	}
+	if len(vs) == 0 {
+		left, right = 0, 0
+	}
+	shift := margin - left
+
+	centre := func(v int) point {
+		return point{
+			X: math.Round(x[v] + shift),
+			Y: margin + float64(vs[v].layer)*(nodeHeight+layerGap) + nodeHeight/2,
+		}
+	}
+
+	l := &graphLayout{
+		positions: make(map[string]position, len(ids)),
+		layers:    layer,
+		bends:     make(map[int][]point),
+		width:     right - left + 2*margin,
+		height:    2*margin + float64(len(layers))*nodeHeight + float64(max(len(layers)-1, 0))*layerGap,
+	}
+	for _, row := range layers {
+		for _, v := range row {
+			if vs[v].id == "" {
+				continue
+			}
+			c := centre(v)
+			l.positions[vs[v].id] = position{X: c.X - nodeWidth/2, Y: c.Y - nodeHeight/2}
+			l.order = append(l.order, vs[v].id)
+		}
+	}
+
+	// Route edges through their dummies, backwards for reversed edges
+	for i, edge := range edges {
+		chain, reversed := chains[[2]string{edge.Source, edge.Target}], false
+		if chain == nil {
+			chain, reversed = chains[[2]string{edge.Target, edge.Source}], true
+		}
+		if len(chain) <= 2 {
+			continue
+		}
+		// Edges cross a layer vertically, so that they only run diagonally
+		// in the gaps between layers, where no box can be in the way
+		var bends []point
+		for _, v := range chain[1 : len(chain)-1] {
+			c := centre(v)
+			bends = append(bends, point{c.X, c.Y - nodeHeight/2}, point{c.X, c.Y + nodeHeight/2})
+		}
+		if reversed {
+			for a, b := 0, len(bends)-1; a < b; a, b = a+1, b-1 {
+				bends[a], bends[b] = bends[b], bends[a]
+			}
+		}
+		l.bends[i] = bends
+	}
+
+	return l
+}
+
+// assignLayers puts each node one layer below its deepest predecessor,
+// then moves nodes without predecessors, such as placeholders for missing
+// proofs, down to sit just above the first node they point to
+func assignLayers(ids []string, succ map[string][]string) map[string]int {
+	indegree := make(map[string]int, len(ids))
+	for _, id := range ids {
+		for _, next := range succ[id] {
+			indegree[next]++
+		}
+	}
+	sources := make(map[string]bool)
+	layer := make(map[string]int, len(ids))
+	var queue []string
+	for _, id := range ids {
+		layer[id] = 0
+		if indegree[id] == 0 {
+			sources[id] = true
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range succ[id] {
+			if layer[id]+1 > layer[next] {
+				layer[next] = layer[id] + 1
+			}
+			if indegree[next]--; indegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if !sources[id] || len(succ[id]) == 0 {
+			continue
+		}
+		lowest := math.MaxInt
+		for _, next := range succ[id] {
+			lowest = min(lowest, layer[next])
+		}
+		layer[id] = lowest - 1
+	}
+	return layer
+}
+
+// orderLayers reduces edge crossings by sorting each layer by the mean
+// position of its neighbours, sweeping down and up in turn, and keeps the
+// ordering with the fewest crossings seen
+func orderLayers(layers [][]int, vs []vertex) [][]int {
+	pos := make([]int, len(vs))
+	for _, row := range layers {
+		for i, v := range row {
+			pos[v] = i
+		}
+	}
+
+	best := cloneLayers(layers)
+	fewest := countCrossings(layers, vs, pos)
+	for sweep := 0; sweep < orderingSweeps && fewest > 0; sweep++ {
+		if sweep%2 == 0 {
+			for l := 1; l < len(layers); l++ {
+				sortByBarycentre(layers[l], pos, func(v int) []int { return vs[v].up })
+			}
+		} else {
+			for l := len(layers) - 2; l >= 0; l-- {
+				sortByBarycentre(layers[l], pos, func(v int) []int { return vs[v].down })
+			}
+		}
+		if crossings := countCrossings(layers, vs, pos); crossings < fewest {
+			best, fewest = cloneLayers(layers), crossings
+		}
+	}
+	return best
+}
+
+// sortByBarycentre reorders a layer by the mean position of each vertex's
+// neighbours on the adjacent layer. Vertices without neighbours keep their
+// current position.
+func sortByBarycentre(row []int, pos []int, neighbours func(v int) []int) {
+	weight := make(map[int]float64, len(row))
+	for _, v := range row {
+		adjacent := neighbours(v)
+		if len(adjacent) == 0 {
+			weight[v] = float64(pos[v])
+			continue
+		}
+		sum := 0.0
+		for _, n := range adjacent {
+			sum += float64(pos[n])
+		}
+		weight[v] = sum / float64(len(adjacent))
+	}
+	sort.SliceStable(row, func(i, j int) bool {
+		return weight[row[i]] < weight[row[j]]
+	})
+	for i, v := range row {
+		pos[v] = i
+	}
+}
+
+// countCrossings counts the pairs of segments that cross between each two
+// adjacent layers
+func countCrossings(layers [][]int, vs []vertex, pos []int) int {
+	total := 0
+	for l := 0; l+1 < len(layers); l++ {
+		var segments [][2]int
+		for _, v := range layers[l] {
+			for _, w := range vs[v].down {
+				segments = append(segments, [2]int{pos[v], pos[w]})
+			}
+		}
+		for i := range segments {
+			for j := i + 1; j < len(segments); j++ {
+				a, b := segments[i], segments[j]
+				if (a[0]-b[0])*(a[1]-b[1]) < 0 {
+					total++
+				}
+			}
+		}
+	}
+	return total
+}
+
+func cloneLayers(layers [][]int) [][]int {
+	out := make([][]int, len(layers))
+	for i, row := range layers {
+		out[i] = append([]int(nil), row...)
+	}
+	return out
+}
+
+// assignCoordinates gives each vertex the x of its centre. Layers start
+// packed from the left, then every pass moves each vertex as close as the
+// layer's order and spacing allow to the mean x of its neighbours, so
+// chains run straight and parents sit over their children.
+func assignCoordinates(layers [][]int, vs []vertex) []float64 {
+	x := make([]float64, len(vs))
+	for _, row := range layers {
+		for i, v := range row {
+			if i > 0 {
+				x[v] = x[row[i-1]] + separation(vs[row[i-1]], vs[v])
+			}
+		}
+	}
+
+	desired := make([]float64, len(vs))
+	for pass := 0; pass < coordinatePasses; pass++ {
+		for i := range layers {
+			l := i
+			if pass%2 == 1 {
+				l = len(layers) - 1 - i
+			}
+			for _, v := range layers[l] {
+				adjacent := append(append([]int(nil), vs[v].up...), vs[v].down...)
+				if len(adjacent) == 0 {
+					desired[v] = x[v]
+					continue
+				}
+				sum := 0.0
+				for _, n := range adjacent {
+					sum += x[n]
+				}
+				desired[v] = sum / float64(len(adjacent))
+			}
+			packLayer(layers[l], vs, desired, x)
+		}
+	}
+	return x
+}
+
+// packLayer places the vertices of a layer as close as possible, in least
+// squares, to their desired x while keeping their order and spacing.
+// Subtracting each vertex's minimum offset from the first turns this into
+// isotonic regression, solved by pooling adjacent violators.
+func packLayer(row []int, vs []vertex, desired, x []float64) {
+	offsets := make([]float64, len(row))
+	for i := 1; i < len(row); i++ {
+		offsets[i] = offsets[i-1] + separation(vs[row[i-1]], vs[row[i]])
+	}
+
+	type block struct {
+		sum   float64
+		count int
+		start int
+	}
+	var blocks []block
+	for i, v := range row {
+		blocks = append(blocks, block{sum: desired[v] - offsets[i], count: 1, start: i})
+		for len(blocks) > 1 {
+			a, b := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			if a.sum/float64(a.count) <= b.sum/float64(b.count) {
+				break
+			}
+			blocks = append(blocks[:len(blocks)-2], block{sum: a.sum + b.sum, count: a.count + b.count, start: a.start})
+		}
+	}
+
+	for j, b := range blocks {
+		end := len(row)
+		if j+1 < len(blocks) {
+			end = blocks[j+1].start
+		}
+		mean := b.sum / float64(b.count)
+		for i := b.start; i < end; i++ {
+			x[row[i]] = mean + offsets[i]
+		}
+	}
+}
+
+// separation is the minimum distance between the centres of two
+// neighbouring vertices on a layer
+func separation(a, b vertex) float64 {
+	gap := float64(dummyGap)
+	if a.id != "" && b.id != "" {
+		gap = nodeGap
+	}
+	return (a.width+b.width)/2 + gap
+}
+
+// acyclicSuccessors lists the successors of each node with the edges that
+// close a cycle reversed, found by depth-first search from the nodes
+// nothing points to, then from the rest in the given order. Self-loops and
+// edges to unknown nodes are left out.
+func acyclicSuccessors(order []string, edges []models.GraphEdge) map[string][]string {
+	known := make(map[string]bool, len(order))
+	for _, id := range order {
+		known[id] = true
+	}
+
+	out := make(map[string][]string, len(order))
+	pointedTo := make(map[string]bool, len(order))
+	seen := make(map[[2]string]bool)
+	for _, edge := range edges {
+		key := [2]string{edge.Source, edge.Target}
+		if edge.Source == edge.Target || !known[edge.Source] || !known[edge.Target] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out[edge.Source] = append(out[edge.Source], edge.Target)
+		pointedTo[edge.Target] = true
+	}
+
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make(map[string]int, len(order))
+	succ := make(map[string][]string, len(order))
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = active
+		for _, next := range out[id] {
+			switch state[next] {
+			case active:
+				succ[next] = append(succ[next], id)
+			case unvisited:
+				succ[id] = append(succ[id], next)
+				visit(next)
+			default:
+				succ[id] = append(succ[id], next)
+			}
+		}
+		state[id] = done
+	}
+	for _, sourcesOnly := range []bool{true, false} {
+		for _, id := range order {
+			if state[id] == unvisited && (!sourcesOnly || !pointedTo[id]) {
+				visit(id)
+			}
+		}
+	}
+	return succ
+}
@@ -0,0 +1,178 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// GenerateReceiptGraph links a receipt to the invocation it ran and to the
+// delegation chain behind that invocation
+func (s *Service) GenerateReceiptGraph(tokenBytes []byte) (*models.ReceiptGraphResponse, error) {
+	receipt, err := s.parser.ParseReceipt(tokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse receipt: %w", err)
+	}
+
+	var nodes []models.GraphNode
+	var edges []models.GraphEdge
+	var chainInfo models.ChainInfo
+
+	// The chain is only known when the receipt carries its invocation
+	if receipt.Ran.Invocation != nil {
+		dag, err := s.parser.ParseInvocationChain(tokenBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse invocation chain: %w", err)
+		}
+		nodes, edges = s.buildDelegationGraph(dag)
+		chainInfo = s.buildChainInfo(dag)
+	}
+
+	receiptNodes, receiptEdges := s.buildReceiptGraph(receipt, nodes)
+	nodes = append(nodes, receiptNodes...)
+	edges = append(edges, receiptEdges...)
+
+	return &models.ReceiptGraphResponse{
+		Nodes:   applyLayout(nodes, edges),
+		Edges:   edges,
+		Chain:   chainInfo,
+		Receipt: receipt,
+	}, nil
+}
+
+// buildReceiptGraph creates the receipt, invocation and effect nodes and the
+// edges tying them to the principals already in the graph
+func (s *Service) buildReceiptGraph(receipt *models.ReceiptResponse, principals []models.GraphNode) ([]models.GraphNode, []models.GraphEdge) {
+	var nodes []models.GraphNode
+	var edges []models.GraphEdge
+
+	known := make(map[string]bool)
+	for _, node := range principals {
+		known[node.ID] = true
+	}
+
+	outcome := "ok"
+	if !receipt.Out.Success {
+		outcome = "error"
+	}
+
+	receiptID := fmt.Sprintf("receipt-%s", receipt.CID)
+	nodes = append(nodes, models.GraphNode{
+		ID:    receiptID,
+		Label: fmt.Sprintf("Receipt (%s)", outcome),
+		Type:  "receipt",
+		Level: -1,
+		Metadata: map[string]interface{}{
+			"cid":            receipt.CID,
+			"success":        receipt.Out.Success,
+			"out":            receipt.Out,
+			"meta":           receipt.Meta,
+			"issuer":         receipt.Issuer,
+			"signatureValid": receipt.Signature.Valid,
+		},
+	})
+
+	// Invocation node, or a placeholder when the receipt only links to it
+	invocationID := fmt.Sprintf("invocation-%s", receipt.Ran.CID)
+	invocationNode := models.GraphNode{
+		ID:    invocationID,
+		Label: fmt.Sprintf("Missing invocation %s", utils.ShortenDID(receipt.Ran.CID)),
+		Type:  "unresolved",
+		Level: 0,
+		Metadata: map[string]interface{}{
+			"cid": receipt.Ran.CID,
+		},
+	}
+	if inv := receipt.Ran.Invocation; inv != nil {
+		invocationNode.Type = "invocation"
+		invocationNode.Label = "Invocation"
+		if len(inv.Capabilities) > 0 {
+			invocationNode.Label = inv.Capabilities[0].Can
+			invocationNode.Metadata["capability"] = inv.Capabilities[0]
+		}
+		invocationNode.Metadata["issuer"] = inv.Issuer
+		invocationNode.Metadata["audience"] = inv.Audience
+
+		edges = append(edges, models.GraphEdge{
+			Source: inv.Issuer,
+			Target: invocationID,
+			Label:  "invokes",
+			Valid:  inv.Signature.Valid,
+			Level:  0,
+			Type:   "invokes",
+			Metadata: map[string]interface{}{
+				"cid": inv.CID,
+			},
+		})
+	}
+	nodes = append(nodes, invocationNode)
+
+	edges = append(edges, models.GraphEdge{
+		Source: invocationID,
+		Target: receiptID,
+		Label:  fmt.Sprintf("ran: %s", outcome),
+		Valid:  receipt.Out.Success,
+		Level:  -1,
+		Type:   "ran",
+		Metadata: map[string]interface{}{
+			"invocationCID": receipt.Ran.CID,
+			"receiptCID":    receipt.CID,
+		},
+	})
+
+	// Executor signing the receipt
+	if receipt.Issuer != "" {
+		if !known[receipt.Issuer] {
+			nodes = append(nodes, models.GraphNode{
+				ID:    receipt.Issuer,
+				Label: utils.ShortenDID(receipt.Issuer),
+				Type:  "executor",
+				Level: -1,
+				Metadata: map[string]interface{}{
+					"fullDID": receipt.Issuer,
+					"role":    "executor",
+				},
+			})
+		}
+		edges = append(edges, models.GraphEdge{
+			Source: receipt.Issuer,
+			Target: receiptID,
+			Label:  "issued",
+			Valid:  receipt.Signature.Valid,
+			Level:  -1,
+			Type:   "issued",
+		})
+	}
+
+	// Effects scheduled by the receipt
+	addEffect := func(link, kind string) {
+		effectID := fmt.Sprintf("effect-%s", link)
+		nodes = append(nodes, models.GraphNode{
+			ID:    effectID,
+			Label: fmt.Sprintf("%s %s", kind, utils.ShortenDID(link)),
+			Type:  "effect",
+			Level: -2,
+			Metadata: map[string]interface{}{
+				"cid":  link,
+				"kind": kind,
+			},
+		})
+		edges = append(edges, models.GraphEdge{
+			Source: receiptID,
+			Target: effectID,
+			Label:  kind,
+			Valid:  true,
+			Level:  -2,
+			Type:   kind,
+		})
+	}
+	for _, link := range receipt.Fx.Fork {
+		addEffect(link, "fork")
+	}
+	if receipt.Fx.Join != "" {
+		addEffect(receipt.Fx.Join, "join")
+	}
+
+	return nodes, edges
+}
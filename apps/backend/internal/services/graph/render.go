@@ -0,0 +1,323 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+)
+
+// Image formats a graph renders to
+const (
+	RenderSVG = "svg"
+	RenderPNG = "png"
+)
+
+// RenderMediaTypes maps each image format to its media type
+var RenderMediaTypes = map[string]string{
+	RenderSVG: "image/svg+xml",
+	RenderPNG: "image/png",
+}
+
+// Drawing geometry, in SVG user units
+const (
+	badgeRadius    = 12
+	textInset      = 48  // left edge of node text, right of the badge
+	labelCharWidth = 6.6 // advance of the 11px monospace font
+	parallelOffset = 18  // spacing between edges joining the same nodes
+	loopReach      = 36  // how far a self-loop extends right of its node
+	arrowLength    = 10
+	arrowWidth     = 5
+)
+
+// nodeLabelChars is how many characters fit on a node
+const nodeLabelChars = 27
+
+type point struct {
+	X, Y float64
+}
+
+// scene is a laid-out graph, ready to be drawn as SVG or PNG
+type scene struct {
+	width, height float64
+	nodes         []sceneNode
+	edges         []sceneEdge
+}
+
+type sceneNode struct {
+	id      string
+	x, y    float64
+	label   string
+	caption string // node type
+	color   string
+	dashed  bool
+	class   string
+}
+
+type sceneEdge struct {
+	points []point // source border to target border
+	label  string
+	labelX float64
+	labelY float64
+	color  string
+	dashed bool
+	class  string
+}
+
+// Render draws the nodes and edges of a graph as an SVG or PNG image,
+// laid out top-down in layers and styled like the frontend's UCAN nodes
+func (s *Service) Render(format string, nodes []models.GraphNode, edges []models.GraphEdge) ([]byte, error) {
+	sc := buildScene(nodes, edges)
+
+	switch format {
+	case RenderSVG:
+		return sc.svg(), nil
+	case RenderPNG:
+		return sc.png()
+	default:
+		return nil, fmt.Errorf("unsupported image format: %q", format)
+	}
+}
+
+// buildScene lays the graph out and routes every edge between node
+// borders, straight or through the bends of edges spanning several layers.
+// Edges joining the same two nodes are spread apart so that each capability
+// keeps a visible, labelled line.
+func buildScene(nodes []models.GraphNode, edges []models.GraphEdge) *scene {
+	layout := layeredLayout(nodes, edges)
+	positions := layout.positions
+	sc := &scene{width: layout.width, height: layout.height}
+
+	for _, node := range nodes {
+		pos := positions[node.ID]
+		sc.nodes = append(sc.nodes, sceneNode{
+			id:      node.ID,
+			x:       pos.X,
+			y:       pos.Y,
+			label:   fitText(node.Label, nodeLabelChars),
+			caption: strings.ToUpper(node.Type),
+			color:   nodeColor(node),
+			dashed:  node.Type == "unresolved",
+			class:   node.Type,
+		})
+	}
+
+	// Count the edges between each pair of nodes, in either direction
+	pairs := make(map[[2]string]int)
+	for _, edge := range edges {
+		pairs[pairKey(edge)]++
+	}
+	seen := make(map[[2]string]int)
+
+	for i, edge := range edges {
+		key := pairKey(edge)
+		offset := (float64(seen[key]) - float64(pairs[key]-1)/2) * parallelOffset
+		seen[key]++
+
+		from, to := positions[edge.Source], positions[edge.Target]
+		var points []point
+		var labelAt point
+		switch {
+		case edge.Source == edge.Target:
+			points, labelAt = loopRoute(from, offset)
+		case len(layout.bends[i]) > 0:
+			points, labelAt = bentRoute(from, to, layout.bends[i], offset)
+		default:
+			points, labelAt = straightRoute(from, to, offset)
+		}
+
+		sc.edges = append(sc.edges, sceneEdge{
+			points: points,
+			label:  edge.Label,
+			labelX: labelAt.X,
+			labelY: labelAt.Y,
+			color:  edgeColor(edge),
+			dashed: edgeDashed(edge),
+			class:  edgeClass(edge),
+		})
+	}
+
+	return sc
+}
+
+// pairKey identifies the unordered pair of nodes an edge joins
+func pairKey(edge models.GraphEdge) [2]string {
+	if edge.Source < edge.Target {
+		return [2]string{edge.Source, edge.Target}
+	}
+	return [2]string{edge.Target, edge.Source}
+}
+
+// straightRoute joins two node boxes by a line between their centres,
+// shifted sideways by offset and clipped to the box borders
+func straightRoute(from, to position, offset float64) ([]point, point) {
+	a := point{from.X + nodeWidth/2, from.Y + nodeHeight/2}
+	b := point{to.X + nodeWidth/2, to.Y + nodeHeight/2}
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := math.Hypot(dx, dy)
+	ux, uy := dx/length, dy/length
+
+	// Offsets run along the normal, oriented the same way for both
+	// directions so that opposite edges do not overlap
+	nx, ny := -uy, ux
+	if dx < 0 || (dx == 0 && dy < 0) {
+		nx, ny = -nx, -ny
+	}
+	a = point{a.X + nx*offset, a.Y + ny*offset}
+	b = point{b.X + nx*offset, b.Y + ny*offset}
+
+	start := boxExit(a, ux, uy, from)
+	end := boxExit(b, -ux, -uy, to)
+	return []point{start, end}, point{(start.X + end.X) / 2, (start.Y + end.Y) / 2}
+}
+
+// bentRoute joins two node boxes through the bends of an edge spanning
+// several layers, shifted sideways by offset. The label sits on the middle
+// bend, or halfway between the two middle ones.
+func bentRoute(from, to position, bends []point, offset float64) ([]point, point) {
+	points := make([]point, 0, len(bends)+2)
+	a := point{from.X + nodeWidth/2 + offset, from.Y + nodeHeight/2}
+	b := point{to.X + nodeWidth/2 + offset, to.Y + nodeHeight/2}
+
+	first, last := bends[0], bends[len(bends)-1]
+	dx, dy := unit(a, point{first.X + offset, first.Y})
+	points = append(points, boxExit(a, dx, dy, from))
+	for _, p := range bends {
+		points = append(points, point{p.X + offset, p.Y})
+	}
+	dx, dy = unit(b, point{last.X + offset, last.Y})
+	points = append(points, boxExit(b, dx, dy, to))
+
+	n := len(points)
+	if n%2 == 1 {
+		return points, points[n/2]
+	}
+	mid := point{(points[n/2-1].X + points[n/2].X) / 2, (points[n/2-1].Y + points[n/2].Y) / 2}
+	return points, mid
+}
+
+// unit returns the direction from a to b
+func unit(a, b point) (float64, float64) {
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length == 0 {
+		return 0, 0
+	}
+	return (b.X - a.X) / length, (b.Y - a.Y) / length
+}
+
+// boxExit finds where a ray from p, inside the node box at pos, leaves it
+func boxExit(p point, dx, dy float64, pos position) point {
+	t := math.Inf(1)
+	if dx > 0 {
+		t = math.Min(t, (pos.X+nodeWidth-p.X)/dx)
+	} else if dx < 0 {
+		t = math.Min(t, (pos.X-p.X)/dx)
+	}
+	if dy > 0 {
+		t = math.Min(t, (pos.Y+nodeHeight-p.Y)/dy)
+	} else if dy < 0 {
+		t = math.Min(t, (pos.Y-p.Y)/dy)
+	}
+	if math.IsInf(t, 1) || t < 0 {
+		t = 0
+	}
+	return point{p.X + dx*t, p.Y + dy*t}
+}
+
+// loopRoute draws a node's edge to itself as a loop off its right side
+func loopRoute(pos position, offset float64) ([]point, point) {
+	right := pos.X + nodeWidth
+	mid := pos.Y + nodeHeight/2 + offset
+	reach := right + loopReach + math.Abs(offset)
+	return []point{
+		{right, mid - 10},
+		{reach, mid - 10},
+		{reach, mid + 10},
+		{right, mid + 10},
+	}, point{reach, mid - 18}
+}
+
+// fitText shortens s to at most n characters, counted in runes so that
+// multi-byte characters are never split
+func fitText(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n || n < 4 {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// svg draws the scene on the frontend's dark canvas: edges first, each
+// with a marker in its own colour, then the node cards on top
+func (sc *scene) svg() []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="ui-monospace, SFMono-Regular, Menlo, monospace">`+"\n",
+		sc.width, sc.height, sc.width, sc.height)
+
+	b.WriteString("<defs>\n")
+	markers := make(map[string]bool)
+	for _, edge := range sc.edges {
+		if markers[edge.color] {
+			continue
+		}
+		markers[edge.color] = true
+		fmt.Fprintf(&b, `<marker id="%s" viewBox="0 0 10 10" refX="10" refY="5" markerUnits="userSpaceOnUse" markerWidth="%d" markerHeight="%d" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="%s"/></marker>`+"\n",
+			markerID(edge.color), arrowLength, arrowLength, edge.color)
+	}
+	b.WriteString("</defs>\n")
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", colorBgPrimary)
+
+	for _, edge := range sc.edges {
+		coords := make([]string, 0, len(edge.points))
+		for _, p := range edge.points {
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", p.X, p.Y))
+		}
+		dash := ""
+		if edge.dashed {
+			dash = ` stroke-dasharray="6 4"`
+		}
+		fmt.Fprintf(&b, `<g class="edge %s"><polyline points="%s" fill="none" stroke="%s" stroke-width="2"%s marker-end="url(#%s)"/>`,
+			edge.class, strings.Join(coords, " "), edge.color, dash, markerID(edge.color))
+		if edge.label != "" {
+			w := float64(utf8.RuneCountInString(edge.label))*labelCharWidth + 8
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="16" rx="3" fill="%s" stroke="%s"/>`,
+				edge.labelX-w/2, edge.labelY-8, w, colorBgSecondary, colorBorder)
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="11" text-anchor="middle" fill="%s">%s</text>`,
+				edge.labelX, edge.labelY+4, edge.color, escapeXML(edge.label))
+		}
+		b.WriteString("</g>\n")
+	}
+
+	for _, node := range sc.nodes {
+		dash := ""
+		if node.dashed {
+			dash = ` stroke-dasharray="6 4"`
+		}
+		fmt.Fprintf(&b, `<g class="node %s"><title>%s</title>`, node.class, escapeXML(node.id))
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%d" height="%d" rx="8" fill="%s" stroke="%s" stroke-width="2"%s/>`,
+			node.x, node.y, nodeWidth, nodeHeight, colorBgSecondary, node.color, dash)
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="%d" fill="%s"/>`,
+			node.x+24, node.y+nodeHeight/2, badgeRadius, node.color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="10" fill="%s">%s</text>`,
+			node.x+textInset, node.y+26, colorTextTertiary, escapeXML(node.caption))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="11" fill="%s">%s</text>`,
+			node.x+textInset, node.y+44, colorTextPrimary, escapeXML(node.label))
+		b.WriteString("</g>\n")
+	}
+
+	b.WriteString("</svg>\n")
+	return []byte(b.String())
+}
+
+func markerID(color string) string {
+	return "arrow-" + strings.TrimPrefix(color, "#")
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
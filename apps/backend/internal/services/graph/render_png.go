@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// pngScale is the number of pixels per SVG user unit, so PNGs stay sharp
+// on high-density screens
+const pngScale = 2
+
+// maxPNGPixels bounds the size of a PNG, and so the memory one render
+// takes; larger graphs are drawn at a smaller scale
+const maxPNGPixels = 8 << 20
+
+// png rasterizes the scene with the same geometry as svg. Text uses the
+// built-in 5x7 bitmap font and shapes are drawn without anti-aliasing,
+// which keeps the renderer free of image dependencies.
+func (sc *scene) png() ([]byte, error) {
+	scale := pngScale * math.Min(1, math.Sqrt(maxPNGPixels/(sc.width*pngScale*sc.height*pngScale)))
+	c := &canvas{
+		img: image.NewRGBA(image.Rect(0, 0,
+			int(math.Ceil(sc.width*scale)), int(math.Ceil(sc.height*scale)))),
+		scale: scale,
+	}
+	c.fillRect(0, 0, sc.width, sc.height, parseHex(colorBgPrimary))
+
+	for _, edge := range sc.edges {
+		col := parseHex(edge.color)
+		for i := 1; i < len(edge.points); i++ {
+			a, b := edge.points[i-1], edge.points[i]
+			if i == len(edge.points)-1 {
+				b = c.arrow(a, b, col)
+			}
+			c.line(a, b, 2, col, edge.dashed)
+		}
+		if edge.label != "" {
+			chars := float64(utf8.RuneCountInString(edge.label))
+			w := chars*(glyphWidth+1) + 8
+			c.fillRoundRect(edge.labelX-w/2, edge.labelY-8, w, 16, 3, parseHex(colorBorder))
+			c.fillRoundRect(edge.labelX-w/2+1, edge.labelY-7, w-2, 14, 2, parseHex(colorBgSecondary))
+			c.text(edge.labelX-chars*(glyphWidth+1)/2, edge.labelY-3, edge.label, col)
+		}
+	}
+
+	for _, node := range sc.nodes {
+		col := parseHex(node.color)
+		c.fillRoundRect(node.x, node.y, nodeWidth, nodeHeight, 8, col)
+		c.fillRoundRect(node.x+2, node.y+2, nodeWidth-4, nodeHeight-4, 6, parseHex(colorBgSecondary))
+		c.fillCircle(node.x+24, node.y+nodeHeight/2, badgeRadius, col)
+		c.text(node.x+textInset, node.y+18, node.caption, parseHex(colorTextTertiary))
+		c.text(node.x+textInset, node.y+36, node.label, parseHex(colorTextPrimary))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canvas draws in SVG user units onto an image, scale pixels to the unit
+type canvas struct {
+	img   *image.RGBA
+	scale float64
+}
+
+func (c *canvas) set(px, py int, col color.RGBA) {
+	if image.Pt(px, py).In(c.img.Rect) {
+		c.img.SetRGBA(px, py, col)
+	}
+}
+
+// fill sets every pixel in the unit-space box whose centre inside reports
+// as covered
+func (c *canvas) fill(x, y, w, h float64, col color.RGBA, inside func(ux, uy float64) bool) {
+	x0, y0 := int(math.Floor(x*c.scale)), int(math.Floor(y*c.scale))
+	x1, y1 := int(math.Ceil((x+w)*c.scale)), int(math.Ceil((y+h)*c.scale))
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			ux, uy := (float64(px)+0.5)/c.scale, (float64(py)+0.5)/c.scale
+			if inside(ux, uy) {
+				c.set(px, py, col)
+			}
+		}
+	}
+}
+
+func (c *canvas) fillRect(x, y, w, h float64, col color.RGBA) {
+	c.fill(x, y, w, h, col, func(ux, uy float64) bool {
+		return ux >= x && ux < x+w && uy >= y && uy < y+h
+	})
+}
+
+func (c *canvas) fillRoundRect(x, y, w, h, r float64, col color.RGBA) {
+	c.fill(x, y, w, h, col, func(ux, uy float64) bool {
+		if ux < x || ux >= x+w || uy < y || uy >= y+h {
+			return false
+		}
+		// Distance from the nearest corner centre, outside the straight edges
+		cx := math.Max(x+r-ux, math.Max(ux-(x+w-r), 0))
+		cy := math.Max(y+r-uy, math.Max(uy-(y+h-r), 0))
+		return cx*cx+cy*cy <= r*r
+	})
+}
+
+func (c *canvas) fillCircle(cx, cy, r float64, col color.RGBA) {
+	c.fill(cx-r, cy-r, 2*r, 2*r, col, func(ux, uy float64) bool {
+		return (ux-cx)*(ux-cx)+(uy-cy)*(uy-cy) <= r*r
+	})
+}
+
+// line strokes a segment of the given width, in 6 on, 4 off dashes when
+// dashed, to match the SVG stroke-dasharray
+func (c *canvas) line(a, b point, width float64, col color.RGBA, dashed bool) {
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length == 0 {
+		return
+	}
+	dx, dy := (b.X-a.X)/length, (b.Y-a.Y)/length
+	step := math.Min(0.5/c.scale, width/2)
+	for t := 0.0; t <= length; t += step {
+		if dashed && math.Mod(t, 10) >= 6 {
+			continue
+		}
+		px, py := a.X+dx*t, a.Y+dy*t
+		c.fillRect(px-width/2, py-width/2, width, width, col)
+	}
+}
+
+// arrow fills an arrowhead with its tip at b and returns the point the
+// line should stop at, the middle of the arrowhead's base
+func (c *canvas) arrow(a, b point, col color.RGBA) point {
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length == 0 {
+		return b
+	}
+	dx, dy := (b.X-a.X)/length, (b.Y-a.Y)/length
+	base := point{b.X - dx*arrowLength, b.Y - dy*arrowLength}
+	left := point{base.X - dy*arrowWidth, base.Y + dx*arrowWidth}
+	right := point{base.X + dy*arrowWidth, base.Y - dx*arrowWidth}
+
+	minX := math.Min(b.X, math.Min(left.X, right.X))
+	minY := math.Min(b.Y, math.Min(left.Y, right.Y))
+	maxX := math.Max(b.X, math.Max(left.X, right.X))
+	maxY := math.Max(b.Y, math.Max(left.Y, right.Y))
+	c.fill(minX, minY, maxX-minX, maxY-minY, col, func(ux, uy float64) bool {
+		p := point{ux, uy}
+		d1, d2, d3 := cross(b, left, p), cross(left, right, p), cross(right, b, p)
+		hasNeg := d1 < 0 || d2 < 0 || d3 < 0
+		hasPos := d1 > 0 || d2 > 0 || d3 > 0
+		return !(hasNeg && hasPos)
+	})
+	return base
+}
+
+func cross(a, b, p point) float64 {
+	return (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+}
+
+// text draws s with its top-left corner at x, y, one unit per font pixel
+func (c *canvas) text(x, y float64, s string, col color.RGBA) {
+	for i, r := range []rune(s) {
+		rows := glyph(r)
+		gx := x + float64(i*(glyphWidth+1))
+		for row, bits := range rows {
+			for column := 0; column < glyphWidth; column++ {
+				if bits&(1<<(glyphWidth-1-column)) != 0 {
+					c.fillRect(gx+float64(column), y+float64(row), 1, 1, col)
+				}
+			}
+		}
+	}
+}
+
+// parseHex reads a #rrggbb colour
+func parseHex(hex string) color.RGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil {
+		return color.RGBA{A: 0xff}
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
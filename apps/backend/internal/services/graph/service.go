@@ -6,34 +6,43 @@ import (
 
 	"github.com/goddhi/ucan-visualizer/internal/models"
 	"github.com/goddhi/ucan-visualizer/internal/services/parser"
+	"github.com/goddhi/ucan-visualizer/internal/services/validator"
 	"github.com/goddhi/ucan-visualizer/pkg/utils"
 )
 
 type Service struct {
-	parser *parser.Service
+	parser    *parser.Service
+	validator *validator.Service
 }
 
 func NewService() *Service {
 	return &Service{
-		parser: parser.NewService(),
+		parser:    parser.NewService(),
+		validator: validator.NewService(),
 	}
 }
 
 // GenerateDelegationGraph creates comprehensive delegation chain visualization
 func (s *Service) GenerateDelegationGraph(tokenBytes []byte) (*models.GraphResponse, error) {
-	chain, err := s.parser.ParseDelegationChain(tokenBytes)
+	dag, err := s.parser.ParseDelegationChain(tokenBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse delegation chain: %w", err)
 	}
 
-	nodes, edges := s.buildDelegationGraph(chain)
-	chainInfo := s.buildChainInfo(chain)
+	return s.GenerateDAGGraph(dag), nil
+}
+
+// GenerateDAGGraph creates the delegation graph of an already parsed DAG
+func (s *Service) GenerateDAGGraph(dag *models.DelegationDAG) *models.GraphResponse {
+	nodes, edges := s.buildDelegationGraph(dag)
+	chainInfo := s.buildChainInfo(dag)
 
 	return &models.GraphResponse{
-		Nodes: nodes,
+		Nodes: applyLayout(nodes, edges),
 		Edges: edges,
 		Chain: chainInfo,
-	}, nil
+		Mode:  ModePrincipals,
+	}
 }
 
 // GenerateInvocationGraph creates enhanced invocation visualization
@@ -43,16 +52,16 @@ func (s *Service) GenerateInvocationGraph(tokenBytes []byte) (*models.Invocation
 		return nil, fmt.Errorf("failed to parse invocation: %w", err)
 	}
 
-	chain, err := s.parser.ParseDelegationChain(tokenBytes)
+	dag, err := s.parser.ParseInvocationChain(tokenBytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse delegation chain: %w", err)
 	}
 
-	nodes, edges := s.buildInvocationGraph(chain, invocation)
-	chainInfo := s.buildChainInfo(chain)
+	nodes, edges := s.buildInvocationGraph(dag, invocation)
+	chainInfo := s.buildChainInfo(dag)
 
 	return &models.InvocationGraphResponse{
-		Nodes:        nodes,
+		Nodes:        applyLayout(nodes, edges),
 		Edges:        edges,
 		Chain:        chainInfo,
 		Invocation:   invocation,
@@ -60,10 +69,14 @@ func (s *Service) GenerateInvocationGraph(tokenBytes []byte) (*models.Invocation
 	}, nil
 }
 
-// buildDelegationGraph creates nodes and edges for delegation visualization
-func (s *Service) buildDelegationGraph(chain []*models.DelegationResponse) ([]models.GraphNode, []models.GraphEdge) {
+// buildDelegationGraph creates nodes and edges for delegation visualization.
+// The edges of a delegation hold when it validates as of now.
+func (s *Service) buildDelegationGraph(dag *models.DelegationDAG) ([]models.GraphNode, []models.GraphEdge) {
 	nodes := make(map[string]*models.GraphNode)
+	var nodeOrder []string // creation order, so the output is stable
 	var edges []models.GraphEdge
+	chain := dag.Ordered()
+	links := chainLinks(s.validator.ValidateDAG(dag, validator.Options{}))
 
 	// Find max level for proper node typing
 	maxLevel := 0
@@ -89,6 +102,7 @@ func (s *Service) buildDelegationGraph(chain []*models.DelegationResponse) ([]mo
 					"proofs":        len(del.Proofs),
 				},
 			}
+			nodeOrder = append(nodeOrder, del.Issuer)
 		}
 
 		// Create audience node
@@ -103,6 +117,33 @@ func (s *Service) buildDelegationGraph(chain []*models.DelegationResponse) ([]mo
 					"role":    "delegatee",
 				},
 			}
+			nodeOrder = append(nodeOrder, del.Audience)
+		}
+
+		// UCAN 1.0 tokens delegate a single command on a subject
+		if env := del.Envelope; env != nil {
+			subject := env.Subject
+			if env.Powerline {
+				subject = "any subject"
+			}
+			edges = append(edges, models.GraphEdge{
+				Source: del.Issuer,
+				Target: del.Audience,
+				Label:  fmt.Sprintf("%s on %s", env.Command, subject),
+				Valid:  links[del.CID].Valid,
+				Level:  del.Level,
+				Type:   env.Kind,
+				Metadata: map[string]interface{}{
+					"command":   env.Command,
+					"subject":   env.Subject,
+					"powerline": env.Powerline,
+					"policy":    env.Policy,
+					"args":      env.Args,
+					"version":   env.Version,
+					"cid":       del.CID,
+				},
+			})
+			continue
 		}
 
 		// Create edges for each capability
@@ -112,7 +153,7 @@ func (s *Service) buildDelegationGraph(chain []*models.DelegationResponse) ([]mo
 				Target:     del.Audience,
 				Capability: cap,
 				Label:      s.createCapabilityLabel(cap, i),
-				Valid:      true,
+				Valid:      links[del.CID].Valid,
 				Level:      del.Level,
 				Type:       "delegation",
 				Metadata: map[string]interface{}{
@@ -120,42 +161,76 @@ func (s *Service) buildDelegationGraph(chain []*models.DelegationResponse) ([]mo
 					"resource":   cap.With,
 					"category":   cap.Category,
 					"cid":        del.CID,
+					"version":    del.Version,
 				},
 			}
 			edges = append(edges, edge)
 		}
+	}
 
-		// Add proof edges if they exist
-		for _, proof := range del.Proofs {
-			// Create proof connection edges
-			proofEdge := models.GraphEdge{
-				Source: fmt.Sprintf("proof-%s", proof.CID),
-				Target: del.Issuer,
-				Label:  fmt.Sprintf("Proof %d", proof.Index),
-				Valid:  true,
-				Level:  del.Level + 1,
-				Type:   "proof",
+	// A resolved proof shows as the capability edges of its own delegation,
+	// so only proofs missing from the token get an edge here, from a
+	// placeholder node standing in for the delegation that was not found
+	for _, proofEdge := range dag.Edges {
+		if proofEdge.Resolved {
+			continue
+		}
+		del := dag.Delegations[proofEdge.Parent]
+		proof := del.Proofs[proofEdge.Index]
+		proofID := fmt.Sprintf("proof-%s", proofEdge.Proof)
+
+		if _, exists := nodes[proofID]; !exists {
+			nodes[proofID] = &models.GraphNode{
+				ID:    proofID,
+				Label: fmt.Sprintf("Missing proof %s", utils.ShortenDID(proofEdge.Proof)),
+				Type:  "unresolved",
+				Level: del.Level + 1,
 				Metadata: map[string]interface{}{
-					"proofCID":  proof.CID,
-					"proofType": proof.Type,
+					"proofCID": proofEdge.Proof,
+					"citedBy":  proofEdge.Parent,
+					"index":    proofEdge.Index,
 				},
 			}
-			edges = append(edges, proofEdge)
+			nodeOrder = append(nodeOrder, proofID)
 		}
+
+		edges = append(edges, models.GraphEdge{
+			Source: proofID,
+			Target: del.Issuer,
+			Label:  fmt.Sprintf("Proof %d", proofEdge.Index),
+			Valid:  false,
+			Level:  del.Level + 1,
+			Type:   "proof",
+			Metadata: map[string]interface{}{
+				"proofCID":  proofEdge.Proof,
+				"proofType": proof.Type,
+				"parentCID": proofEdge.Parent,
+				"resolved":  false,
+			},
+		})
 	}
 
 	// Convert nodes map to slice
 	var nodeSlice []models.GraphNode
-	for _, node := range nodes {
-		nodeSlice = append(nodeSlice, *node)
+	for _, id := range nodeOrder {
+		nodeSlice = append(nodeSlice, *nodes[id])
 	}
 
 	return nodeSlice, edges
 }
 
+// chainLinks indexes the links of a validation by delegation CID
+func chainLinks(validation *models.ValidationResult) map[string]models.ChainLink {
+	links := make(map[string]models.ChainLink, len(validation.Chain))
+	for _, link := range validation.Chain {
+		links[link.CID] = link
+	}
+	return links
+}
+
 // buildInvocationGraph creates enhanced visualization for invocations
-func (s *Service) buildInvocationGraph(chain []*models.DelegationResponse, invocation *models.InvocationResponse) ([]models.GraphNode, []models.GraphEdge) {
-	nodes, edges := s.buildDelegationGraph(chain)
+func (s *Service) buildInvocationGraph(dag *models.DelegationDAG, invocation *models.InvocationResponse) ([]models.GraphNode, []models.GraphEdge) {
+	nodes, edges := s.buildDelegationGraph(dag)
 
 	// Enhance visualization for invocations
 	if invocation.IsInvocation && invocation.Task != nil {
@@ -174,9 +249,9 @@ func (s *Service) buildInvocationGraph(chain []*models.DelegationResponse, invoc
 			}
 		}
 
-		// Mark invocation edges
+		// Mark the edges of the invocation itself, not delegations between the same principals
 		for i, edge := range edges {
-			if edge.Source == invocation.Task.Issuer && edge.Target == invocation.Task.Target {
+			if edge.Metadata["cid"] == invocation.Task.CID {
 				edges[i].Type = "invocation"
 				edges[i].Label = fmt.Sprintf("INVOKE: %s", edge.Label)
 				edges[i].Metadata["isInvocation"] = true
@@ -187,6 +262,7 @@ func (s *Service) buildInvocationGraph(chain []*models.DelegationResponse, invoc
 				if invocation.InvocationAnalysis != nil {
 					edges[i].Metadata["hasInvokeCapability"] = invocation.InvocationAnalysis.HasInvokeCapability
 					edges[i].Metadata["invokePatterns"] = invocation.InvocationAnalysis.InvokePatterns
+					edges[i].Metadata["source"] = invocation.InvocationAnalysis.Source
 				}
 			}
 		}
@@ -214,12 +290,14 @@ func (s *Service) buildInvocationGraph(chain []*models.DelegationResponse, invoc
 }
 
 // buildChainInfo creates comprehensive chain analysis
-func (s *Service) buildChainInfo(chain []*models.DelegationResponse) models.ChainInfo {
+func (s *Service) buildChainInfo(dag *models.DelegationDAG) models.ChainInfo {
+	chain := dag.Ordered()
 	if len(chain) == 0 {
 		return models.ChainInfo{}
 	}
 
 	principals := make(map[string]*models.PrincipalInfo)
+	var principalOrder []string
 	var timeline []models.TimelineEvent
 	maxLevel := 0
 	var proofChain *models.ProofChain
@@ -232,8 +310,8 @@ func (s *Service) buildChainInfo(chain []*models.DelegationResponse) models.Chai
 	}
 
 	// Build proof chain structure
-	if maxLevel > 0 || s.hasProofs(chain) {
-		proofChain = s.buildProofChain(chain)
+	if maxLevel > 0 || len(dag.Edges) > 0 {
+		proofChain = s.buildProofChain(dag)
 	}
 
 	// Process each delegation
@@ -248,6 +326,7 @@ func (s *Service) buildChainInfo(chain []*models.DelegationResponse) models.Chai
 					Level: del.Level,
 					CIDs:  []string{del.CID},
 				}
+				principalOrder = append(principalOrder, did)
 			} else {
 				principals[did].CIDs = append(principals[did].CIDs, del.CID)
 			}
@@ -276,32 +355,39 @@ func (s *Service) buildChainInfo(chain []*models.DelegationResponse) models.Chai
 	}
 
 	// Sort timeline
-	sort.Slice(timeline, func(i, j int) bool {
+	sort.SliceStable(timeline, func(i, j int) bool {
 		return timeline[i].Time.Before(timeline[j].Time)
 	})
 
 	// Convert principals to slice
 	var principalSlice []models.PrincipalInfo
-	for _, principal := range principals {
-		principalSlice = append(principalSlice, *principal)
+	for _, did := range principalOrder {
+		principalSlice = append(principalSlice, *principals[did])
 	}
 
-	// Get leaf CIDs
+	// Get leaf CIDs: delegations that rest on no further resolved proof
+	cited := make(map[string]bool)
+	for _, edge := range dag.Edges {
+		if edge.Resolved {
+			cited[edge.Parent] = true
+		}
+	}
 	var leafCIDs []string
 	for _, del := range chain {
-		if del.Level == maxLevel {
+		if !cited[del.CID] {
 			leafCIDs = append(leafCIDs, del.CID)
 		}
 	}
 
 	return models.ChainInfo{
-		TotalLevels: maxLevel + 1,
-		IsComplete:  true,
-		RootCID:     chain[0].CID,
-		LeafCIDs:    leafCIDs,
-		Principals:  principalSlice,
-		Timeline:    timeline,
-		ProofChain:  proofChain,
+		TotalLevels:      maxLevel + 1,
+		IsComplete:       len(dag.Unresolved) == 0,
+		RootCID:          dag.Root,
+		LeafCIDs:         leafCIDs,
+		Principals:       principalSlice,
+		Timeline:         timeline,
+		ProofChain:       proofChain,
+		UnresolvedProofs: dag.Unresolved,
 	}
 }
 
@@ -351,19 +437,10 @@ func (s *Service) getPrincipalRole(did string, level, maxLevel int) string {
 	}
 }
 
-func (s *Service) hasProofs(chain []*models.DelegationResponse) bool {
-	for _, del := range chain {
-		if len(del.Proofs) > 0 {
-			return true
-		}
-	}
-	return false
-}
-
-func (s *Service) buildProofChain(chain []*models.DelegationResponse) *models.ProofChain {
+func (s *Service) buildProofChain(dag *models.DelegationDAG) *models.ProofChain {
 	levels := make(map[int]*models.ProofLevel)
 	
-	for _, del := range chain {
+	for _, del := range dag.Ordered() {
 		if _, exists := levels[del.Level]; !exists {
 			levels[del.Level] = &models.ProofLevel{
 				Level:       del.Level,
@@ -391,9 +468,9 @@ func (s *Service) buildProofChain(chain []*models.DelegationResponse) *models.Pr
 	})
 	
 	return &models.ProofChain{
-		Root:       chain[0].CID,
+		Root:       dag.Root,
 		Levels:     levelSlice,
-		IsComplete: true,
+		IsComplete: len(dag.Unresolved) == 0,
 		TotalDepth: len(levelSlice),
 	}
 }
@@ -403,5 +480,6 @@ func (s *Service) getCategoryNames(categories map[string][]models.CapabilityInfo
 	for category := range categories {
 		names = append(names, category)
 	}
+	sort.Strings(names)
 	return names
 }
\ No newline at end of file
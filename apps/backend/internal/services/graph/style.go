@@ -0,0 +1,63 @@
+package graph
+
+import "github.com/goddhi/ucan-visualizer/internal/models"
+
+// Colours of the frontend theme (apps/frontend/app/globals.css), so
+// exported diagrams look like the canvas they were taken from
+const (
+	colorBgPrimary       = "#0a0a0f"
+	colorBgSecondary     = "#13131a"
+	colorBorder          = "#2a2a38"
+	colorTextPrimary     = "#e5e7eb"
+	colorAccentPrimary   = "#6366f1"
+	colorAccentSecondary = "#a78bfa"
+	colorAccentDark      = "#4f46e5"
+	colorTextTertiary    = "#6b7280"
+	colorBorderAccent    = "#3a3a48"
+	colorSuccess         = "#10b981"
+	colorWarning         = "#f59e0b"
+	colorError           = "#ef4444"
+	colorNodeText        = "#ffffff"
+)
+
+// nodeColors maps node types to their fill
+var nodeColors = map[string]string{
+	"root":         colorAccentPrimary,
+	"intermediate": colorAccentSecondary,
+	"leaf":         colorSuccess,
+	"invoker":      colorWarning,
+	"unresolved":   colorError,
+	"summary":      colorTextTertiary,
+}
+
+// edgeColors maps valid edge types to their stroke; invalid edges are
+// always drawn in the error colour
+var edgeColors = map[string]string{
+	"delegation": colorAccentPrimary,
+	"invocation": colorWarning,
+	"proof":      colorAccentSecondary,
+	"proves":     colorAccentSecondary,
+}
+
+func nodeColor(node models.GraphNode) string {
+	if color, ok := nodeColors[node.Type]; ok {
+		return color
+	}
+	return colorBorderAccent
+}
+
+func edgeColor(edge models.GraphEdge) string {
+	if !edge.Valid {
+		return colorError
+	}
+	if color, ok := edgeColors[edge.Type]; ok {
+		return color
+	}
+	return colorAccentPrimary
+}
+
+// edgeDashed reports whether an edge is drawn dashed: proofs, and edges
+// that do not hold
+func edgeDashed(edge models.GraphEdge) bool {
+	return !edge.Valid || edge.Type == "proof"
+}
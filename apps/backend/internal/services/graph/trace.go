@@ -0,0 +1,297 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/validator"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// maxTracePaths bounds how many proof paths a trace follows, since shared
+// proofs can make their number grow exponentially with depth
+const maxTracePaths = 64
+
+// Trace hop statuses
+const (
+	hopOrigin     = "origin"     // the capability starts here
+	hopUnchanged  = "unchanged"  // passed on as received
+	hopAttenuated = "attenuated" // passed on narrowed
+	hopBroadened  = "broadened"  // claimed beyond what the proof grants
+	hopDropped    = "dropped"    // not passed on although the proof grants it
+	hopAbsent     = "absent"     // neither held nor passed on
+	hopUnproven   = "unproven"   // claimed on proofs missing from the token
+)
+
+// TraceCapability follows one capability through the delegation chain of a
+// token to the delegations issued to principal
+func (s *Service) TraceCapability(tokenBytes []byte, principal string, query models.CapabilityInfo) (*models.CapabilityTrace, error) {
+	dag, err := s.parser.ParseDelegationChain(tokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse delegation chain: %w", err)
+	}
+
+	return s.TraceDAGCapability(dag, principal, query), nil
+}
+
+// TraceDAGCapability follows one capability through an already parsed DAG.
+// Every delegation issued to principal is traced back through the proofs
+// carrying the capability, or through all its proofs when none does, to
+// where the capability originates. Each path is then walked from the origin
+// down, checking every hop's form of the capability against the one before.
+// An empty principal means the audience of the root delegation.
+func (s *Service) TraceDAGCapability(dag *models.DelegationDAG, principal string, query models.CapabilityInfo) *models.CapabilityTrace {
+	if principal == "" {
+		if root := dag.RootDelegation(); root != nil {
+			principal = root.Audience
+		}
+	}
+
+	trace := &models.CapabilityTrace{
+		Principal: principal,
+		Can:       query.Can,
+		With:      query.With,
+		Paths:     []models.TracePath{},
+		Nodes:     []models.GraphNode{},
+		Edges:     []models.GraphEdge{},
+	}
+
+	// UCAN 1.0 delegations carry a command rather than capabilities
+	var targets []*models.DelegationResponse
+	for _, del := range dag.Ordered() {
+		if del.Audience == principal && del.Envelope == nil {
+			targets = append(targets, del)
+		}
+	}
+	if len(targets) == 0 {
+		trace.Reason = fmt.Sprintf("No delegation in the chain is issued to %s", principal)
+		return trace
+	}
+
+	for _, target := range targets {
+		for _, path := range proofPaths(dag, target, query) {
+			if len(trace.Paths) == maxTracePaths {
+				break
+			}
+			trace.Paths = append(trace.Paths, traceHops(path, query))
+		}
+	}
+
+	for _, path := range trace.Paths {
+		trace.Granted = trace.Granted || path.Granted
+	}
+	if !trace.Granted {
+		for _, path := range trace.Paths {
+			if hop := firstBreak(path); hop != nil {
+				trace.Break = hop
+				trace.Reason = hop.Issue
+				break
+			}
+		}
+		if trace.Break == nil {
+			trace.Reason = fmt.Sprintf("No delegation issued to %s carries %s", principal, describeQuery(query))
+		}
+	}
+
+	trace.Nodes, trace.Edges = s.traceSubgraph(dag, trace.Paths)
+	return trace
+}
+
+// proofPaths lists the chains of delegations leading to del, each from the
+// delegation where it starts down to del. A path stops at a delegation
+// holding the capability on its issuer's own resource, or one without
+// resolved proofs.
+func proofPaths(dag *models.DelegationDAG, del *models.DelegationResponse, query models.CapabilityInfo) [][]*models.DelegationResponse {
+	var paths [][]*models.DelegationResponse
+	onPath := make(map[string]bool)
+
+	var walk func(del *models.DelegationResponse, below []*models.DelegationResponse)
+	walk = func(del *models.DelegationResponse, below []*models.DelegationResponse) {
+		if len(paths) == maxTracePaths {
+			return
+		}
+
+		var carrying, resolved []*models.DelegationResponse
+		if form := carriedForm(del, query, nil); form == nil || form.With != del.Issuer {
+			for _, proof := range del.Proofs {
+				proofDel, ok := dag.Delegations[proof.CID]
+				if !ok || onPath[proofDel.CID] {
+					continue
+				}
+				resolved = append(resolved, proofDel)
+				if carriedForm(proofDel, query, nil) != nil {
+					carrying = append(carrying, proofDel)
+				}
+			}
+		}
+		if len(carrying) > 0 {
+			resolved = carrying
+		}
+
+		if len(resolved) == 0 {
+			path := []*models.DelegationResponse{del}
+			for i := len(below) - 1; i >= 0; i-- {
+				path = append(path, below[i])
+			}
+			paths = append(paths, path)
+			return
+		}
+
+		onPath[del.CID] = true
+		for _, proofDel := range resolved {
+			walk(proofDel, append(below, del))
+		}
+		delete(onPath, del.CID)
+	}
+	walk(del, nil)
+
+	return paths
+}
+
+// traceHops checks each delegation of a path against the one before it
+func traceHops(path []*models.DelegationResponse, query models.CapabilityInfo) models.TracePath {
+	traced := models.TracePath{Granted: true}
+	var prev *models.CapabilityInfo
+
+	for i, del := range path {
+		form := carriedForm(del, query, prev)
+		hop := models.TraceHop{
+			CID:        del.CID,
+			Issuer:     del.Issuer,
+			Audience:   del.Audience,
+			Level:      del.Level,
+			Capability: form,
+		}
+		ref := fmt.Sprintf("delegation %s from %s to %s", utils.ShortenDID(del.CID), utils.ShortenDID(del.Issuer), utils.ShortenDID(del.Audience))
+
+		switch {
+		case form == nil && prev == nil:
+			hop.Status = hopAbsent
+		case form == nil:
+			hop.Status = hopDropped
+			hop.Issue = fmt.Sprintf("%s is dropped by %s", describeQuery(query), ref)
+		case form.With == del.Issuer || (i == 0 && len(del.Proofs) == 0):
+			hop.Status = hopOrigin
+		case i == 0:
+			hop.Status = hopUnproven
+			hop.Issue = fmt.Sprintf("%s claims %s, but none of its proofs is in the token", ref, describeCapability(*form))
+		case prev == nil:
+			hop.Status = hopBroadened
+			hop.Issue = fmt.Sprintf("%s claims %s, which its proof does not grant", ref, describeCapability(*form))
+		default:
+			check := validator.CheckAttenuation(*prev, *form)
+			hop.Attenuation = &check
+			switch {
+			case !check.Valid:
+				hop.Status = hopBroadened
+				hop.Issue = fmt.Sprintf("%s broadens %s: %s", ref, describeCapability(*prev), strings.Join(check.Issues, "; "))
+			case sameCapability(*prev, *form):
+				hop.Status = hopUnchanged
+			default:
+				hop.Status = hopAttenuated
+			}
+		}
+
+		traced.Hops = append(traced.Hops, hop)
+		prev = form
+	}
+
+	// Granted when the principal ends up holding the capability and no hop
+	// along the way loses it
+	traced.Granted = prev != nil && firstBreak(traced) == nil
+	return traced
+}
+
+// firstBreak returns the first hop of a path where the capability is
+// dropped, broadened or unproven
+func firstBreak(path models.TracePath) *models.TraceHop {
+	for i, hop := range path.Hops {
+		if hop.Issue != "" {
+			return &path.Hops[i]
+		}
+	}
+	return nil
+}
+
+// carriedForm returns the capability of del covering the query, preferring
+// one that the previous hop's form covers in turn, or nil if del does not
+// carry the query
+func carriedForm(del *models.DelegationResponse, query models.CapabilityInfo, prev *models.CapabilityInfo) *models.CapabilityInfo {
+	var found *models.CapabilityInfo
+	for _, cap := range del.Capabilities {
+		if !validator.AbilityCovers(cap.Can, query.Can) {
+			continue
+		}
+		if query.With != "" && !validator.ResourceCovers(cap.With, query.With) {
+			continue
+		}
+		if prev != nil && validator.CheckAttenuation(*prev, cap).Valid {
+			return &cap
+		}
+		if found == nil {
+			found = &cap
+		}
+	}
+	return found
+}
+
+func sameCapability(a, b models.CapabilityInfo) bool {
+	return a.Can == b.Can && a.With == b.With &&
+		validator.CaveatsNarrow(a.Nb, b.Nb) && validator.CaveatsNarrow(b.Nb, a.Nb)
+}
+
+func describeQuery(query models.CapabilityInfo) string {
+	if query.With == "" {
+		return query.Can
+	}
+	return describeCapability(query)
+}
+
+func describeCapability(cap models.CapabilityInfo) string {
+	return fmt.Sprintf("%s on %s", cap.Can, cap.With)
+}
+
+// traceSubgraph keeps the capability edges of the principal graph that
+// carry the traced capability, marking each with its hop status, and the
+// principals they join. A delegation on several paths keeps its best
+// status.
+func (s *Service) traceSubgraph(dag *models.DelegationDAG, paths []models.TracePath) ([]models.GraphNode, []models.GraphEdge) {
+	hops := make(map[string]models.TraceHop)
+	for _, path := range paths {
+		for _, hop := range path.Hops {
+			if hop.Capability == nil {
+				continue
+			}
+			if seen, ok := hops[hop.CID]; !ok || (seen.Issue != "" && hop.Issue == "") {
+				hops[hop.CID] = hop
+			}
+		}
+	}
+
+	nodes, edges := s.buildDelegationGraph(dag)
+
+	kept := []models.GraphEdge{}
+	endpoints := make(map[string]bool)
+	for _, edge := range edges {
+		cid, _ := edge.Metadata["cid"].(string)
+		hop, ok := hops[cid]
+		if !ok || edge.Capability.Can != hop.Capability.Can || edge.Capability.With != hop.Capability.With {
+			continue
+		}
+		edge.Valid = hop.Issue == ""
+		edge.Metadata["trace"] = hop.Status
+		kept = append(kept, edge)
+		endpoints[edge.Source] = true
+		endpoints[edge.Target] = true
+	}
+
+	var keptNodes []models.GraphNode
+	for _, node := range nodes {
+		if endpoints[node.ID] {
+			keptNodes = append(keptNodes, node)
+		}
+	}
+
+	return applyLayout(keptNodes, kept), kept
+}
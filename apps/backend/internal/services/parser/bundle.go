@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"fmt"
+
+	ucantoipld "github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/message"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+)
+
+// How a bundle root leads to its delegation
+const (
+	RootArchive      = "archive"       // ucanto archive block linking a delegation
+	RootDelegation   = "delegation"    // the root is a delegation block itself
+	RootAgentMessage = "agent_message" // a task executed by an agent message
+	RootToken        = "token"         // not a CAR: the token is its own root
+)
+
+// ParseBundle parses every root of a token into its own delegation DAG.
+// delegation.Extract stops at a single root; bundles such as `w3 proof`
+// exports and batch archives carry several, and an agent message may
+// execute several tasks. Roots are followed through ucanto archive blocks
+// and agent messages to the delegations they hold. A token that is not a
+// CAR is a bundle of one.
+func (s *Service) ParseBundle(tokenBytes []byte) (*models.Bundle, error) {
+	archive, err := readArchive(tokenBytes)
+	if err != nil {
+		dag, err := s.ParseDelegationChain(tokenBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &models.Bundle{
+			Chains: []models.BundleChain{{Root: dag.Root, Source: RootToken, DAG: dag}},
+		}, nil
+	}
+
+	bundle := &models.Bundle{Chains: []models.BundleChain{}}
+	for _, root := range archive.roots {
+		targets, source := bundleTargets(archive, root)
+		for _, target := range targets {
+			del, err := archive.delegationView(target)
+			if err != nil {
+				bundle.Skipped = append(bundle.Skipped, models.SkippedRoot{
+					CID:    target.String(),
+					Reason: err.Error(),
+				})
+				continue
+			}
+			bundle.Chains = append(bundle.Chains, models.BundleChain{
+				Root:   root.String(),
+				Source: source,
+				DAG:    s.parseChain(del),
+			})
+		}
+	}
+	if len(bundle.Chains) == 0 {
+		return nil, fmt.Errorf("no CAR root holds a delegation (%d skipped)", len(bundle.Skipped))
+	}
+
+	// Each failed block belongs to the chain citing it, else the first one
+	for _, failure := range archive.failures {
+		chain := bundle.Chains[0]
+		for _, candidate := range bundle.Chains {
+			if citesProof(candidate.DAG, failure.CID) {
+				chain = candidate
+				break
+			}
+		}
+		assignIntegrity(chain.DAG, []models.BlockIntegrity{failure})
+	}
+
+	return bundle, nil
+}
+
+// bundleTargets lists the delegations a CAR root leads to
+func bundleTargets(archive *archiveBlocks, root ucantoipld.Link) ([]ucantoipld.Link, string) {
+	if link, ok := archivedLink(archive.reader, root); ok {
+		return []ucantoipld.Link{link}, RootArchive
+	}
+	if msg, err := message.NewMessage(root, archive.reader); err == nil {
+		return msg.Invocations(), RootAgentMessage
+	}
+	return []ucantoipld.Link{root}, RootDelegation
+}
+
+// citesProof reports whether any delegation in dag cites cid as a proof
+func citesProof(dag *models.DelegationDAG, cid string) bool {
+	for _, edge := range dag.Edges {
+		if edge.Proof == cid {
+			return true
+		}
+	}
+	return false
+}
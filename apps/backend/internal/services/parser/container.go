@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// containerKey is the top-level key of a UCAN 1.0 container
+const containerKey = "ctn-v1"
+
+// containerRawHeader marks a container serialized as raw DAG-CBOR
+const containerRawHeader = '@'
+
+// decodeContainer returns the tokens bundled in a UCAN 1.0 container, a
+// DAG-CBOR map {"ctn-v1": [token bytes, ...]} optionally preceded by its
+// "@" header byte
+func decodeContainer(data []byte) ([][]byte, error) {
+	if len(data) > 0 && data[0] == containerRawHeader {
+		data = data[1:]
+	}
+
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	node := nb.Build()
+	if node.Kind() != ipld.Kind_Map {
+		return nil, fmt.Errorf("not a UCAN container")
+	}
+
+	list, err := node.LookupByString(containerKey)
+	if err != nil || list.Kind() != ipld.Kind_List {
+		return nil, fmt.Errorf("not a UCAN container: missing %s list", containerKey)
+	}
+
+	var tokens [][]byte
+	iter := list.ListIterator()
+	for !iter.Done() {
+		_, item, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		token, err := item.AsBytes()
+		if err != nil {
+			return nil, fmt.Errorf("container entry is not a token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("UCAN container holds no tokens")
+	}
+
+	return tokens, nil
+}
+
+// parseContainer parses a UCAN 1.0 container into a delegation DAG. Tokens
+// are addressed by their DAG-CBOR CID, which is how prf links cite them.
+// The root is the invocation when there is one, otherwise the first token
+// no other token cites.
+func (s *Service) parseContainer(data []byte) (*models.DelegationDAG, error) {
+	tokens, err := decodeContainer(data)
+	if err != nil {
+		return nil, err
+	}
+
+	byCID := make(map[string]*models.DelegationResponse, len(tokens))
+	var parsedOrder []*models.DelegationResponse
+	for i, token := range tokens {
+		parsed, err := utils.ParseUnverifiedCBOR(token)
+		if err != nil {
+			return nil, fmt.Errorf("container token %d: %w", i, err)
+		}
+
+		del := s.mapRawTokenToModel(parsed, token)
+		if _, dup := byCID[del.CID]; dup {
+			continue
+		}
+		byCID[del.CID] = del
+		parsedOrder = append(parsedOrder, del)
+	}
+
+	cited := make(map[string]bool)
+	for _, del := range parsedOrder {
+		for _, proof := range del.Proofs {
+			cited[proof.CID] = true
+		}
+	}
+
+	var root *models.DelegationResponse
+	for _, del := range parsedOrder {
+		if del.Envelope != nil && del.Envelope.Kind == EnvelopeInvocation {
+			root = del
+			break
+		}
+		if root == nil && !cited[del.CID] {
+			root = del
+		}
+	}
+	if root == nil {
+		root = parsedOrder[0]
+	}
+
+	dag := newDAG(root)
+	queue := []*models.DelegationResponse{root}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		for i, proof := range parent.Proofs {
+			proofDel, ok := byCID[proof.CID]
+			parent.Proofs[i].Resolved = ok
+			addProofEdge(dag, parent, parent.Proofs[i])
+
+			if !ok {
+				continue
+			}
+			if _, seen := dag.Delegations[proof.CID]; seen {
+				continue
+			}
+			proofDel.Level = parent.Level + 1
+			dag.Delegations[proofDel.CID] = proofDel
+			dag.Order = append(dag.Order, proofDel.CID)
+			queue = append(queue, proofDel)
+		}
+	}
+
+	return dag, nil
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// Envelope kinds
+const (
+	EnvelopeDelegation = "delegation"
+	EnvelopeInvocation = "invocation"
+)
+
+// mapEnvelope exposes the native fields of a UCAN 1.0 envelope, or returns
+// nil for tokens that are not 1.0 envelopes
+func (s *Service) mapEnvelope(parsed *utils.ParsedJWT) *models.Envelope {
+	if parsed.Envelope == "" {
+		return nil
+	}
+
+	claims := parsed.Claims
+	name, version, _ := strings.Cut(parsed.Envelope, "@")
+
+	envelope := &models.Envelope{
+		Tag:       parsed.Envelope,
+		Kind:      envelopeKind(name),
+		Version:   version,
+		Header:    decodeVarsig(parsed.VarsigHeader),
+		Command:   claims.Command,
+		Subject:   claims.Subject,
+		Powerline: claims.Powerline,
+		Policy:    claims.Policy,
+		Args:      claims.Args,
+		Meta:      claims.Meta,
+		Cause:     claims.Cause,
+	}
+	if claims.IssuedAt != 0 {
+		envelope.IssuedAt = time.Unix(claims.IssuedAt, 0)
+	}
+
+	return envelope
+}
+
+// envelopeKind names the token kind a payload tag such as "ucan/dlg" stands for
+func envelopeKind(name string) string {
+	switch name {
+	case "ucan/dlg":
+		return EnvelopeDelegation
+	case "ucan/inv", "ucan/i":
+		return EnvelopeInvocation
+	default:
+		return strings.TrimPrefix(name, "ucan/")
+	}
+}
+
+func decodeVarsig(h []byte) models.VarsigInfo {
+	info := models.VarsigInfo{
+		Raw: base64.StdEncoding.EncodeToString(h),
+	}
+
+	header, err := utils.DecodeVarsigHeader(h)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Algorithm = header.Algorithm
+	info.KeyType = header.KeyType
+	info.Hash = header.Hash
+	info.PayloadEncoding = header.PayloadEncoding
+	return info
+}
@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	_ "github.com/ipld/go-ipld-prime/codec/raw"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	ipldmc "github.com/ipld/go-ipld-prime/multicodec"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multicodec"
+	"github.com/multiformats/go-multihash"
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+	ucantoipld "github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/ipld/block"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// InspectCAR lists every block of a CAR with its codec, hash function and
+// decoded content, and notes which blocks decode as delegations and what
+// they link to. It reports on blocks rather than UCANs, so it works on
+// archives the other endpoints cannot parse.
+func (s *Service) InspectCAR(data []byte) (*models.CARInspection, error) {
+	car, err := utils.ReadCAR(data)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &models.CARInspection{
+		Version:     car.Version,
+		Roots:       []string{},
+		Blocks:      []models.BlockInfo{},
+		TotalSize:   len(data),
+		Delegations: []models.BlockDelegation{},
+	}
+
+	roots := make(map[string]bool, len(car.Roots))
+	for _, root := range car.Roots {
+		roots[root.String()] = true
+		inspection.Roots = append(inspection.Roots, root.String())
+	}
+
+	blocks := make([]ucantoipld.Block, 0, len(car.Blocks))
+	index := make(map[string]int, len(car.Blocks))
+	for _, blk := range car.Blocks {
+		info := inspectBlock(blk)
+		info.IsRoot = roots[info.CID]
+		if _, dup := index[info.CID]; !dup {
+			index[info.CID] = len(inspection.Blocks)
+		}
+		inspection.Blocks = append(inspection.Blocks, info)
+		blocks = append(blocks, block.NewBlock(cidlink.Link{Cid: blk.CID}, blk.Data))
+	}
+
+	br, err := blockstore.NewBlockReader(blockstore.WithBlocks(blocks))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, blk := range car.Blocks {
+		info := &inspection.Blocks[i]
+		if blk.CID.Prefix().Codec != cid.DagCBOR || index[info.CID] != i {
+			continue
+		}
+		del, err := delegation.NewDelegationView(cidlink.Link{Cid: blk.CID}, br)
+		if err != nil {
+			continue
+		}
+
+		entry := models.BlockDelegation{
+			CID:        info.CID,
+			Issuer:     del.Issuer().DID().String(),
+			Audience:   del.Audience().DID().String(),
+			Proofs:     []string{},
+			References: info.Links,
+		}
+		for _, proof := range del.Proofs() {
+			entry.Proofs = append(entry.Proofs, proof.String())
+		}
+		inspection.Delegations = append(inspection.Delegations, entry)
+
+		for _, link := range info.Links {
+			if j, ok := index[link]; ok {
+				inspection.Blocks[j].ReferencedBy = append(inspection.Blocks[j].ReferencedBy, info.CID)
+			}
+		}
+	}
+
+	return inspection, nil
+}
+
+// inspectBlock describes a block from its CID and bytes alone
+func inspectBlock(blk utils.CARBlock) models.BlockInfo {
+	prefix := blk.CID.Prefix()
+	info := models.BlockInfo{
+		CID:          blk.CID.String(),
+		Codec:        multicodec.Code(prefix.Codec).String(),
+		Multihash:    multihash.Codes[prefix.MhType],
+		Size:         len(blk.Data),
+		Offset:       blk.Offset,
+		Links:        []string{},
+		ReferencedBy: []string{},
+	}
+	if info.Multihash == "" {
+		info.Multihash = fmt.Sprintf("0x%x", prefix.MhType)
+	}
+
+	info.HashMatch = utils.VerifyBlock(blk.CID, blk.Data) == nil
+
+	decode, err := ipldmc.LookupDecoder(prefix.Codec)
+	if err != nil {
+		info.DecodeError = fmt.Sprintf("unsupported codec %s", info.Codec)
+		return info
+	}
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := decode(nb, bytes.NewReader(blk.Data)); err != nil {
+		info.DecodeError = err.Error()
+		return info
+	}
+	node := nb.Build()
+
+	var buf bytes.Buffer
+	if err := dagjson.Encode(node, &buf); err != nil {
+		info.DecodeError = err.Error()
+		return info
+	}
+	info.Content = buf.Bytes()
+	info.Links = collectLinks(node, info.Links)
+
+	return info
+}
+
+// collectLinks appends the CIDs of every link within node, depth-first
+func collectLinks(node ipld.Node, links []string) []string {
+	switch node.Kind() {
+	case ipld.Kind_Link:
+		if link, err := node.AsLink(); err == nil {
+			links = append(links, link.String())
+		}
+	case ipld.Kind_Map:
+		iter := node.MapIterator()
+		for !iter.Done() {
+			_, v, err := iter.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	case ipld.Kind_List:
+		iter := node.ListIterator()
+		for !iter.Done() {
+			_, v, err := iter.Next()
+			if err != nil {
+				break
+			}
+			links = collectLinks(v, links)
+		}
+	}
+	return links
+}
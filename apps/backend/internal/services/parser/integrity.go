@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/delegation"
+	ucantoipld "github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/ipld/block"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// archiveVariant is the root block key under which ucanto archives link
+// the delegation they carry
+const archiveVariant = "ucan@0.9.1"
+
+// archiveBlocks is a CAR read block by block, noting the blocks that fail
+// to hash to their CID instead of rejecting the archive
+type archiveBlocks struct {
+	roots      []ucantoipld.Link
+	reader     blockstore.BlockReader
+	failures   []models.BlockIntegrity
+	mismatches map[string]*utils.IntegrityError
+}
+
+// readArchive reads and verifies every block of a CAR
+func readArchive(data []byte) (*archiveBlocks, error) {
+	car, err := utils.ReadCAR(data)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &archiveBlocks{mismatches: make(map[string]*utils.IntegrityError)}
+	for _, root := range car.Roots {
+		archive.roots = append(archive.roots, cidlink.Link{Cid: root})
+	}
+
+	blocks := make([]ucantoipld.Block, 0, len(car.Blocks))
+	for _, blk := range car.Blocks {
+		var mismatch *utils.IntegrityError
+		if errors.As(utils.VerifyBlock(blk.CID, blk.Data), &mismatch) {
+			archive.mismatches[blk.CID.String()] = mismatch
+			archive.failures = append(archive.failures, models.BlockIntegrity{
+				CID:      mismatch.CID.String(),
+				Computed: mismatch.Computed.String(),
+			})
+		}
+		blocks = append(blocks, block.NewBlock(cidlink.Link{Cid: blk.CID}, blk.Data))
+	}
+
+	archive.reader, err = blockstore.NewBlockReader(blockstore.WithBlocks(blocks))
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// delegationView reads the delegation at link, reporting a tampered
+// delegation block as a *utils.IntegrityError
+func (a *archiveBlocks) delegationView(link ucantoipld.Link) (delegation.Delegation, error) {
+	del, err := delegation.NewDelegationView(link, a.reader)
+	if err != nil {
+		if mismatch, ok := a.mismatches[link.String()]; ok {
+			return nil, fmt.Errorf("delegation block: %w", mismatch)
+		}
+		return nil, err
+	}
+	return del, nil
+}
+
+// extractDelegation extracts the delegation a CAR archive carries.
+// delegation.Extract hashes every block as it reads them and rejects the
+// whole archive when one does not match its CID. Such archives are read
+// again block by block so the delegation can still be shown, and the
+// offending blocks are returned alongside it. ucanto rehashes each block it
+// decodes as a UCAN, so a tampered proof stays unresolved; a tampered root
+// delegation is returned as a *utils.IntegrityError.
+func extractDelegation(data []byte) (delegation.Delegation, []models.BlockIntegrity, error) {
+	del, err := delegation.Extract(data)
+	if err == nil {
+		return del, nil, nil
+	}
+
+	archive, carErr := readArchive(data)
+	if carErr != nil || len(archive.roots) != 1 || len(archive.failures) == 0 {
+		// Rejected for some other reason
+		return nil, nil, err
+	}
+
+	root := archive.roots[0]
+	if link, ok := archivedLink(archive.reader, root); ok {
+		root = link
+	}
+	del, viewErr := archive.delegationView(root)
+	if viewErr != nil {
+		var mismatch *utils.IntegrityError
+		if errors.As(viewErr, &mismatch) {
+			return nil, nil, viewErr
+		}
+		return nil, nil, fmt.Errorf("%w; reading blocks individually: %v", err, viewErr)
+	}
+
+	return del, archive.failures, nil
+}
+
+// archivedLink follows the variant root block of a ucanto archive to the
+// delegation it links
+func archivedLink(br blockstore.BlockReader, root ucantoipld.Link) (ucantoipld.Link, bool) {
+	rt, ok, err := br.Get(root)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(rt.Bytes())); err != nil {
+		return nil, false
+	}
+	node := nb.Build()
+	if node.Kind() != ipld.Kind_Map {
+		return nil, false
+	}
+
+	variant, err := node.LookupByString(archiveVariant)
+	if err != nil {
+		return nil, false
+	}
+	link, err := variant.AsLink()
+	if err != nil {
+		return nil, false
+	}
+	return link, true
+}
+
+// assignIntegrity attaches each failed block to the delegation citing it as
+// a proof, or to the root delegation when no delegation in the DAG does,
+// as for the archive's variant root
+func assignIntegrity(dag *models.DelegationDAG, failures []models.BlockIntegrity) {
+	for _, failure := range failures {
+		del := dag.RootDelegation()
+		for _, edge := range dag.Edges {
+			if edge.Proof == failure.CID {
+				del = dag.Delegations[edge.Parent]
+				break
+			}
+		}
+		del.Integrity = append(del.Integrity, failure)
+	}
+}
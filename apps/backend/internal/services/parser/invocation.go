@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/storacha/go-ucanto/core/car"
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// Where an invocation was found
+const (
+	SourceAgentMessage = "agent_message"
+	SourceReceipt      = "receipt"
+	SourceUCANEnvelope = "ucan_envelope"
+	SourceDelegation   = "delegation"
+)
+
+// locatedInvocation is an invocation recovered from the structure of a
+// token. Exactly one of inv, raw and dag is set.
+type locatedInvocation struct {
+	inv        invocation.Invocation // ucanto invocation
+	raw        *utils.ParsedJWT      // UCAN 1.0 invocation envelope
+	dag        *models.DelegationDAG // UCAN 1.0 container rooted at an invocation
+	source     string
+	receiptCID string
+}
+
+// locateInvocation finds an invocation by structure alone: the first task
+// executed by a ucanto agent message, the task a receipt ran, or a UCAN 1.0
+// invocation envelope, alone or in a container with its proofs. A bare
+// UCAN 0.9 archive is indistinguishable from a delegation, so it yields
+// nil.
+func (s *Service) locateInvocation(tokenBytes []byte) *locatedInvocation {
+	if found := s.invocationFromMessage(tokenBytes); found != nil {
+		return found
+	}
+
+	if rcpt, err := receipt.Extract(tokenBytes); err == nil {
+		if inv, ok := rcpt.Ran().Invocation(); ok {
+			return &locatedInvocation{
+				inv:        inv,
+				source:     SourceReceipt,
+				receiptCID: rcpt.Root().Link().String(),
+			}
+		}
+	}
+
+	if dag, err := s.parseContainer(tokenBytes); err == nil {
+		if env := dag.RootDelegation().Envelope; env != nil && env.Kind == EnvelopeInvocation {
+			return &locatedInvocation{
+				dag:    dag,
+				source: SourceUCANEnvelope,
+			}
+		}
+	}
+
+	if parsed, err := utils.ParseUnverifiedCBOR(tokenBytes); err == nil && isInvocationEnvelope(parsed.Envelope) {
+		return &locatedInvocation{
+			raw:    parsed,
+			source: SourceUCANEnvelope,
+		}
+	}
+
+	return nil
+}
+
+// invocationFromMessage decodes a ucanto agent message CAR and returns the
+// first invocation it executes
+func (s *Service) invocationFromMessage(tokenBytes []byte) *locatedInvocation {
+	roots, blks, err := car.Decode(bytes.NewReader(tokenBytes))
+	if err != nil || len(roots) != 1 {
+		return nil
+	}
+
+	br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(blks))
+	if err != nil {
+		return nil
+	}
+
+	msg, err := message.NewMessage(roots[0], br)
+	if err != nil {
+		return nil
+	}
+
+	for _, link := range msg.Invocations() {
+		inv, ok, err := msg.Invocation(link)
+		if err != nil || !ok {
+			continue
+		}
+
+		found := &locatedInvocation{
+			inv:    inv,
+			source: SourceAgentMessage,
+		}
+		// Get assumes the message carries a report
+		if len(msg.Receipts()) > 0 {
+			if rcpt, ok := msg.Get(link); ok {
+				found.receiptCID = rcpt.String()
+			}
+		}
+		return found
+	}
+
+	return nil
+}
+
+// isInvocationEnvelope reports whether a UCAN 1.0 payload tag names an
+// invocation, e.g. "ucan/inv@1.0.0-rc.1"
+func isInvocationEnvelope(tag string) bool {
+	name, _, found := strings.Cut(tag, "@")
+	return found && envelopeKind(name) == EnvelopeInvocation
+}
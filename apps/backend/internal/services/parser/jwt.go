@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// jwtBundle holds the JWTs supplied alongside a UCAN 0.x token, which prf
+// CIDs are resolved against
+type jwtBundle struct {
+	tokens []string
+	byCID  map[string]string // default raw sha2-256 CID to JWT
+}
+
+func newJWTBundle(tokens []string) *jwtBundle {
+	bundle := &jwtBundle{
+		tokens: tokens,
+		byCID:  make(map[string]string, len(tokens)),
+	}
+	for _, token := range tokens {
+		if id, err := utils.TokenCID([]byte(token)); err == nil {
+			bundle.byCID[id.String()] = token
+		}
+	}
+	return bundle
+}
+
+// resolve finds the JWT a prf CID cites. A CID using another codec or hash
+// function is checked by rehashing each JWT with its prefix, so a JWT is
+// only ever returned for a CID that actually addresses it.
+func (b *jwtBundle) resolve(cited string) (string, bool) {
+	if token, ok := b.byCID[cited]; ok {
+		return token, true
+	}
+
+	c, err := cid.Decode(cited)
+	if err != nil {
+		return "", false
+	}
+	for _, token := range b.tokens {
+		if id, err := c.Prefix().Sum([]byte(token)); err == nil && id.Equals(c) {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// parseJWTChain parses a UCAN 0.x JWT together with its proofs. The input
+// holds one or more whitespace-separated JWTs: the first that no other one
+// cites is the token, the rest a bundle its prf CIDs resolve against. Proofs
+// carried inline in prf, as UCAN 0.8 does, are parsed in place.
+func (s *Service) parseJWTChain(tokenBytes []byte) (*models.DelegationDAG, error) {
+	fields := strings.Fields(string(tokenBytes))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no JWT found")
+	}
+
+	var tokens []*utils.ParsedJWT
+	for i, field := range fields {
+		if !utils.IsJWT(field) {
+			return nil, fmt.Errorf("entry %d is not a JWT", i)
+		}
+		parsed, err := utils.ParseUnverifiedJWT(field)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		tokens = append(tokens, parsed)
+	}
+
+	bundle := newJWTBundle(fields)
+	cited := make(map[string]bool)
+	for _, parsed := range tokens {
+		for _, proof := range parsed.Claims.Proofs {
+			if token, ok := s.proofJWT(proof, bundle); ok {
+				cited[token] = true
+			}
+		}
+	}
+
+	rootIndex := 0
+	for i, field := range fields {
+		if !cited[field] {
+			rootIndex = i
+			break
+		}
+	}
+
+	root := s.mapRawTokenToModel(tokens[rootIndex], []byte(fields[rootIndex]))
+	dag := newDAG(root)
+
+	type pending struct {
+		del    *models.DelegationResponse
+		parsed *utils.ParsedJWT
+	}
+	queue := []pending{{root, tokens[rootIndex]}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		parent := current.del
+
+		for i, raw := range current.parsed.Claims.Proofs {
+			token, ok := s.proofJWT(raw, bundle)
+			parent.Proofs[i].Resolved = ok
+			addProofEdge(dag, parent, parent.Proofs[i])
+
+			if !ok {
+				continue
+			}
+			if _, seen := dag.Delegations[parent.Proofs[i].CID]; seen {
+				continue
+			}
+
+			parsed, err := utils.ParseUnverifiedJWT(token)
+			if err != nil {
+				continue
+			}
+			proofDel := s.mapRawTokenToModel(parsed, []byte(token))
+			// Keyed by the CID it was cited with, which resolve has verified
+			proofDel.CID = parent.Proofs[i].CID
+			proofDel.Level = parent.Level + 1
+
+			dag.Delegations[proofDel.CID] = proofDel
+			dag.Order = append(dag.Order, proofDel.CID)
+			queue = append(queue, pending{proofDel, parsed})
+		}
+	}
+
+	return dag, nil
+}
+
+// proofJWT returns the JWT behind a prf entry: the entry itself when the
+// proof is inline, otherwise the bundled JWT its CID addresses
+func (s *Service) proofJWT(entry string, bundle *jwtBundle) (string, bool) {
+	if utils.IsJWT(entry) {
+		return entry, true
+	}
+	return bundle.resolve(entry)
+}
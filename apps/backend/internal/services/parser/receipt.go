@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/result"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// ParseReceipt decodes a ucanto receipt CAR
+func (s *Service) ParseReceipt(tokenBytes []byte) (*models.ReceiptResponse, error) {
+	rcpt, err := receipt.Extract(tokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract receipt: %w", err)
+	}
+
+	// The signature and effects are read from the raw outcome block
+	outcome, err := outcomeNode(rcpt)
+	if err != nil {
+		return nil, err
+	}
+
+	ran := rcpt.Ran()
+	parsed := &models.ReceiptResponse{
+		CID:  rcpt.Root().Link().String(),
+		Ran:  models.RanInfo{CID: ran.Link().String()},
+		Out:  receiptOutcome(rcpt),
+		Fx:   receiptEffects(outcome),
+		Meta: make(map[string]interface{}),
+	}
+
+	for k, v := range rcpt.Meta() {
+		parsed.Meta[k] = utils.AnyToValue(v)
+	}
+
+	if inv, ok := ran.Invocation(); ok {
+		if invocation, err := s.parseDelegationFromUCAN(inv, 0); err == nil {
+			markResolvedProofs(invocation, inv)
+			parsed.Ran.Invocation = invocation
+		}
+	}
+
+	// Without iss the receipt is signed by the principal the invocation was addressed to
+	if iss := rcpt.Issuer(); iss != nil {
+		parsed.Issuer = iss.DID().String()
+	} else if parsed.Ran.Invocation != nil {
+		parsed.Issuer = parsed.Ran.Invocation.Audience
+	}
+
+	for i, proof := range rcpt.Proofs() {
+		_, resolved := proof.Delegation()
+		parsed.Proofs = append(parsed.Proofs, models.ProofInfo{
+			CID:      proof.Link().String(),
+			Index:    i,
+			Type:     "delegation",
+			Resolved: resolved,
+		})
+	}
+
+	parsed.Signature = s.verifyReceiptSignature(rcpt, parsed.Issuer, outcome)
+	return parsed, nil
+}
+
+// outcomeNode decodes the ocm field of a receipt root block
+func outcomeNode(rcpt receipt.AnyReceipt) (ipld.Node, error) {
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(rcpt.Root().Bytes())); err != nil {
+		return nil, fmt.Errorf("failed to decode receipt block: %w", err)
+	}
+
+	outcome, err := nb.Build().LookupByString("ocm")
+	if err != nil {
+		return nil, fmt.Errorf("receipt has no outcome: %w", err)
+	}
+	return outcome, nil
+}
+
+func receiptOutcome(rcpt receipt.AnyReceipt) models.ReceiptOutcome {
+	ok, failure := result.Unwrap(rcpt.Out())
+	if ok != nil {
+		return models.ReceiptOutcome{Success: true, Ok: utils.NodeToValue(ok)}
+	}
+	return models.ReceiptOutcome{Error: utils.NodeToValue(failure)}
+}
+
+func receiptEffects(outcome ipld.Node) models.ReceiptEffects {
+	effects := models.ReceiptEffects{Fork: []string{}}
+
+	fx, err := outcome.LookupByString("fx")
+	if err != nil {
+		return effects
+	}
+
+	if fork, err := fx.LookupByString("fork"); err == nil {
+		iter := fork.ListIterator()
+		for iter != nil && !iter.Done() {
+			_, item, err := iter.Next()
+			if err != nil {
+				break
+			}
+			if link, err := item.AsLink(); err == nil {
+				effects.Fork = append(effects.Fork, link.String())
+			}
+		}
+	}
+
+	if join, err := fx.LookupByString("join"); err == nil {
+		if link, err := join.AsLink(); err == nil {
+			effects.Join = link.String()
+		}
+	}
+
+	return effects
+}
@@ -1,8 +1,6 @@
 package parser
 
 import (
-	"crypto/sha256" 
-	"encoding/hex"  
 	"fmt"
 	"time"
 
@@ -19,25 +17,31 @@ func NewService() *Service {
 	return &Service{}
 }
 
-func (s *Service) verifySignature(del delegation.Delegation) models.SignatureInfo {
-	return models.SignatureInfo{
-		Algorithm: "EdDSA",
-		Verified:  true,
-		Valid:     true,
-	}
-}
-
 // ParseDelegation parses a UCAN delegation from CAR format OR Raw Token
 func (s *Service) ParseDelegation(tokenBytes []byte) (*models.DelegationResponse, error) {
-	del, err := delegation.Extract(tokenBytes)
+	del, failures, err := extractDelegation(tokenBytes)
 	if err == nil {
-		return s.parseDelegationFromUCAN(del, 0)
+		parsed, err := s.parseDelegationFromUCAN(del, 0)
+		if err != nil {
+			return nil, err
+		}
+		markResolvedProofs(parsed, del)
+		parsed.Integrity = failures
+		return parsed, nil
+	}
+
+	if dag, err := s.parseContainer(tokenBytes); err == nil {
+		return dag.RootDelegation(), nil
 	}
 
 	if parsedJWT, err := utils.ParseUnverifiedCBOR(tokenBytes); err == nil {
 		return s.mapRawTokenToModel(parsedJWT, tokenBytes), nil
 	}
 
+	if dag, err := s.parseJWTChain(tokenBytes); err == nil {
+		return dag.RootDelegation(), nil
+	}
+
 	if parsedJWT, err := utils.ParseUnverifiedJWT(string(tokenBytes)); err == nil {
 		return s.mapRawTokenToModel(parsedJWT, tokenBytes), nil
 	}
@@ -45,18 +49,30 @@ func (s *Service) ParseDelegation(tokenBytes []byte) (*models.DelegationResponse
 	return nil, fmt.Errorf("failed to extract delegation: %w", err)
 }
 
-// ParseDelegationChain parses delegation chain
-func (s *Service) ParseDelegationChain(tokenBytes []byte) ([]*models.DelegationResponse, error) {
+// ParseDelegationChain parses a token and its proofs into a delegation DAG
+func (s *Service) ParseDelegationChain(tokenBytes []byte) (*models.DelegationDAG, error) {
 	// 1. Try CAR
-	del, err := delegation.Extract(tokenBytes)
+	del, failures, err := extractDelegation(tokenBytes)
 	if err == nil {
-		return s.parseChain(del), nil
+		dag := s.parseChain(del)
+		assignIntegrity(dag, failures)
+		return dag, nil
+	}
+
+	// 2. UCAN 1.0 container bundling a token with its proofs
+	if dag, err := s.parseContainer(tokenBytes); err == nil {
+		return dag, nil
+	}
+
+	// 3. UCAN 0.x JWT with inline or bundled proofs
+	if dag, err := s.parseJWTChain(tokenBytes); err == nil {
+		return dag, nil
 	}
 
-	// 2. Fallback: Raw Token
+	// 4. Fallback: Raw Token
 	single, err := s.ParseDelegation(tokenBytes)
 	if err == nil {
-		return []*models.DelegationResponse{single}, nil
+		return rawDAG(single), nil
 	}
 
 	return nil, fmt.Errorf("failed to extract delegation chain: %w", err)
@@ -68,8 +84,9 @@ func (s *Service) mapRawTokenToModel(parsed *utils.ParsedJWT, originalBytes []by
 	
 	cid := claims.Cid
 	if cid == "" {
-		hash := sha256.Sum256(originalBytes)
-		cid = "b" + hex.EncodeToString(hash[:])[:40] 
+		if id, err := utils.TokenCID(originalBytes); err == nil {
+			cid = id.String()
+		}
 	}
 	var caps []models.CapabilityInfo
 	for _, att := range claims.Att {
@@ -94,39 +111,89 @@ func (s *Service) mapRawTokenToModel(parsed *utils.ParsedJWT, originalBytes []by
 	// Convert Proofs
 	var proofs []models.ProofInfo
 	for i, p := range claims.Proofs {
-		proofs = append(proofs, models.ProofInfo{
+		proof := models.ProofInfo{
 			CID:   p,
 			Index: i,
 			Type:  "delegation",
-		})
+		}
+		// UCAN 0.8 carries proofs inline as JWTs instead of citing their CIDs
+		if utils.IsJWT(p) {
+			if id, err := utils.TokenCID([]byte(p)); err == nil {
+				proof.CID = id.String()
+				proof.Inline = true
+			}
+		}
+		proofs = append(proofs, proof)
+	}
+
+	// Absent time bounds stay zero rather than becoming the Unix epoch
+	var expiration, notBefore, issuedAt time.Time
+	if claims.Expiry != 0 {
+		expiration = time.Unix(claims.Expiry, 0)
+	}
+	if claims.NotBefore != 0 {
+		notBefore = time.Unix(claims.NotBefore, 0)
+	}
+	if claims.IssuedAt != 0 {
+		issuedAt = time.Unix(claims.IssuedAt, 0)
 	}
 
+	// A 1.0 invocation without aud is addressed to its subject
+	audience := claims.Audience
+	if audience == "" && isInvocationEnvelope(parsed.Envelope) {
+		audience = claims.Subject
+	}
+
+	version, inferred := rawTokenVersion(parsed)
+
 	return &models.DelegationResponse{
-		Issuer:       claims.Issuer,
-		Audience:     claims.Audience,
-		Expiration:   time.Unix(claims.Expiry, 0),
-		NotBefore:    time.Unix(claims.NotBefore, 0),
-		Nonce:        claims.Nonce,
-		Facts:        claims.Facts,
-		Capabilities: caps,
-		Proofs:       proofs,
-		Signature: models.SignatureInfo{
-			Algorithm: "EdDSA",
-			Verified:  false, 
-			Valid:     true, 
-		},
-		CID:   cid, 
-		Level: 0,
+		Issuer:          claims.Issuer,
+		Audience:        audience,
+		Expiration:      expiration,
+		NotBefore:       notBefore,
+		IssuedAt:        issuedAt,
+		Nonce:           claims.Nonce,
+		Facts:           claims.Facts,
+		Capabilities:    caps,
+		Proofs:          proofs,
+		Signature:       s.verifyRawSignature(parsed),
+		CID:             cid,
+		Level:           0,
+		Envelope:        s.mapEnvelope(parsed),
+		Version:         version,
+		VersionInferred: inferred,
+		Header:          jwtHeader(parsed.Header),
+		AttShape:        claims.AttShape,
 	}
 }
+
+// ParseInvocation parses a token and reports whether it carries an invocation.
+// Invocations are recognised structurally: tasks executed by a ucanto agent
+// message, the task a receipt ran, or a UCAN 1.0 invocation envelope.
 func (s *Service) ParseInvocation(tokenBytes []byte) (*models.InvocationResponse, error) {
-	delegation, err := s.ParseDelegation(tokenBytes)
+	found := s.locateInvocation(tokenBytes)
+
+	var delegation *models.DelegationResponse
+	var err error
+	switch {
+	case found == nil:
+		delegation, err = s.ParseDelegation(tokenBytes)
+	case found.dag != nil:
+		delegation = found.dag.RootDelegation()
+	case found.raw != nil:
+		delegation = s.mapRawTokenToModel(found.raw, tokenBytes)
+	default:
+		delegation, err = s.parseDelegationFromUCAN(found.inv, 0)
+		if err == nil {
+			markResolvedProofs(delegation, found.inv)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	invocationAnalysis := s.analyzeInvocation(delegation)
-	capabilityAnalysis := s.analyzeCapabilities(delegation.Capabilities)
+	invocationAnalysis := s.analyzeInvocation(delegation, found)
+	capabilityAnalysis := s.analyzeCapabilities(delegation.Capabilities, invocationAnalysis.IsInvocation)
 
 	var task *models.TaskInfo
 	if invocationAnalysis.IsInvocation {
@@ -138,6 +205,8 @@ func (s *Service) ParseInvocation(tokenBytes []byte) (*models.InvocationResponse
 			Target:      delegation.Audience,
 			TaskType:    invocationAnalysis.TaskType,
 			Permissions: invocationAnalysis.RequiredPermissions,
+			CID:         delegation.CID,
+			ReceiptCID:  found.receiptCID,
 		}
 	}
 
@@ -150,6 +219,22 @@ func (s *Service) ParseInvocation(tokenBytes []byte) (*models.InvocationResponse
 	}, nil
 }
 
+// ParseInvocationChain parses the delegation DAG behind the invocation a token
+// carries, falling back to the token itself when it holds no invocation
+func (s *Service) ParseInvocationChain(tokenBytes []byte) (*models.DelegationDAG, error) {
+	found := s.locateInvocation(tokenBytes)
+	switch {
+	case found == nil:
+		return s.ParseDelegationChain(tokenBytes)
+	case found.dag != nil:
+		return found.dag, nil
+	case found.raw != nil:
+		return rawDAG(s.mapRawTokenToModel(found.raw, tokenBytes)), nil
+	default:
+		return s.parseChain(found.inv), nil
+	}
+}
+
 // parseDelegationFromUCAN converts go-ucanto delegation to our model
 func (s *Service) parseDelegationFromUCAN(del delegation.Delegation, level int) (*models.DelegationResponse, error) {
 	// Parse capabilities
@@ -187,7 +272,7 @@ func (s *Service) parseDelegationFromUCAN(del delegation.Delegation, level int)
 	// Parse facts
 	var facts []interface{}
 	for _, fact := range del.Facts() {
-		facts = append(facts, fact)
+		facts = append(facts, utils.AnyToValue(fact))
 	}
 
 	return &models.DelegationResponse{
@@ -200,101 +285,167 @@ func (s *Service) parseDelegationFromUCAN(del delegation.Delegation, level int)
 		Facts:        facts,
 		Nonce:        string(del.Nonce()),
 		Signature:    s.verifySignature(del),
-		CID:   del.Link().String(),
-		Level: level,
+		CID:          del.Link().String(),
+		Level:        level,
+		Version:      specVersion(del.Version()),
 	}, nil
 }
 
-// parseChain extracts full delegation chain
-func (s *Service) parseChain(del delegation.Delegation) []*models.DelegationResponse {
-	var chain []*models.DelegationResponse
-	
-	// Parse root delegation
+// parseChain walks the proofs of del breadth-first, parsing each distinct
+// delegation once at the shallowest level it is cited from
+func (s *Service) parseChain(del delegation.Delegation) *models.DelegationDAG {
 	root, _ := s.parseDelegationFromUCAN(del, 0)
-	chain = append(chain, root)
+	dag := newDAG(root)
 
-	// Parse proof chain recursively
-	if len(del.Proofs()) > 0 {
-		br, _ := blockstore.NewBlockReader(blockstore.WithBlocksIterator(del.Blocks()))
-		chain = append(chain, s.parseProofs(del.Proofs(), br, 1)...)
+	if len(del.Proofs()) == 0 {
+		return dag
 	}
 
-	return chain
-}
+	br, _ := blockstore.NewBlockReader(blockstore.WithBlocksIterator(del.Blocks()))
+
+	queue := []delegation.Delegation{del}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		parent := dag.Delegations[current.Link().String()]
 
-// parseProofs recursively processes proof delegations
-func (s *Service) parseProofs(proofLinks []ipld.Link, br blockstore.BlockReader, level int) []*models.DelegationResponse {
-	var proofs []*models.DelegationResponse
+		for i, link := range current.Proofs() {
+			proofDel, err := delegation.NewDelegationView(link, br)
+			if err == nil {
+				// Block present in the CAR
+				parent.Proofs[i].Resolved = true
+			}
+			addProofEdge(dag, parent, parent.Proofs[i])
 
-	for _, link := range proofLinks {
-		if proofDel, err := delegation.NewDelegationView(link, br); err == nil {
-			if parsed, err := s.parseDelegationFromUCAN(proofDel, level); err == nil {
-				proofs = append(proofs, parsed)
+			if err != nil {
+				continue
 			}
-			
-			// Recurse into nested proofs
-			if len(proofDel.Proofs()) > 0 {
-				proofs = append(proofs, s.parseProofs(proofDel.Proofs(), br, level+1)...)
+			if _, seen := dag.Delegations[link.String()]; seen {
+				continue
 			}
+
+			parsed, err := s.parseDelegationFromUCAN(proofDel, parent.Level+1)
+			if err != nil {
+				continue
+			}
+			dag.Delegations[parsed.CID] = parsed
+			dag.Order = append(dag.Order, parsed.CID)
+			queue = append(queue, proofDel)
 		}
-    }
-    return proofs
+	}
+
+	return dag
 }
 
-// Comprehensive invocation analysis
-func (s *Service) analyzeInvocation(delegation *models.DelegationResponse) *models.InvocationAnalysis {
-	analysis := &models.InvocationAnalysis{
-		IsInvocation:        false,
-		HasInvokeCapability: false,
-		TaskType:           "delegation",
-		InvokePatterns:     []string{},
-		RequiredPermissions: []string{},
+// markResolvedProofs flags the proofs of parsed whose blocks travel with del
+func markResolvedProofs(parsed *models.DelegationResponse, del delegation.Delegation) {
+	br, err := blockstore.NewBlockReader(blockstore.WithBlocksIterator(del.Blocks()))
+	if err != nil {
+		return
 	}
+	for i, link := range del.Proofs() {
+		if _, err := delegation.NewDelegationView(link, br); err == nil {
+			parsed.Proofs[i].Resolved = true
+		}
+	}
+}
 
-	// Check for different issuer and audience (delegation vs invocation)
-	if delegation.Issuer != delegation.Audience {
-		analysis.IsInvocation = true
+// rawDAG builds the DAG of a raw token, whose proofs are only ever cited
+func rawDAG(single *models.DelegationResponse) *models.DelegationDAG {
+	dag := newDAG(single)
+	for _, proof := range single.Proofs {
+		addProofEdge(dag, single, proof)
 	}
+	return dag
+}
 
-	// Analyze capabilities for invocation patterns
-	for _, cap := range delegation.Capabilities {
-		// Check for explicit invoke capabilities
-		if s.isInvokeCapability(cap.Can) {
-			analysis.HasInvokeCapability = true
-			analysis.TaskType = "invocation"
-			analysis.InvokePatterns = append(analysis.InvokePatterns, cap.Can)
-			analysis.PrimaryAction = cap.Can
-			analysis.TargetResource = cap.With
-		}
+// newDAG starts a delegation DAG rooted at root
+func newDAG(root *models.DelegationResponse) *models.DelegationDAG {
+	return &models.DelegationDAG{
+		Root:        root.CID,
+		Delegations: map[string]*models.DelegationResponse{root.CID: root},
+		Order:       []string{root.CID},
+	}
+}
 
-		// Extract required permissions
+// addProofEdge records that parent cites proof, tracking it as unresolved
+// when its delegation is unavailable
+func addProofEdge(dag *models.DelegationDAG, parent *models.DelegationResponse, proof models.ProofInfo) {
+	dag.Edges = append(dag.Edges, models.ProofEdge{
+		Parent:   parent.CID,
+		Proof:    proof.CID,
+		Index:    proof.Index,
+		Resolved: proof.Resolved,
+	})
+
+	if !proof.Resolved {
+		dag.Unresolved = append(dag.Unresolved, models.UnresolvedProof{
+			CID:     proof.CID,
+			CitedBy: parent.CID,
+			Index:   proof.Index,
+			Level:   parent.Level + 1,
+		})
+	}
+}
+
+// Comprehensive invocation analysis. found is the invocation located in the
+// token, or nil when the token is a plain delegation.
+func (s *Service) analyzeInvocation(delegation *models.DelegationResponse, found *locatedInvocation) *models.InvocationAnalysis {
+	analysis := &models.InvocationAnalysis{
+		IsInvocation:        found != nil,
+		TaskType:            "delegation",
+		Source:              SourceDelegation,
+		InvokePatterns:      []string{},
+		RequiredPermissions: []string{},
+		Constraints:         make(map[string]interface{}),
+	}
+
+	// Extract required permissions
+	for _, cap := range delegation.Capabilities {
 		if cap.Can != "" {
 			analysis.RequiredPermissions = append(analysis.RequiredPermissions, cap.Can)
 		}
 	}
 
-	// Determine task type based on capabilities
-	if analysis.HasInvokeCapability {
-		analysis.TaskType = "invocation"
-	} else if len(delegation.Capabilities) > 0 {
-		analysis.TaskType = "delegation"
+	if len(delegation.Capabilities) > 0 {
 		analysis.PrimaryAction = delegation.Capabilities[0].Can
 		analysis.TargetResource = delegation.Capabilities[0].With
 	}
 
-	// Extract constraints from capabilities
-	analysis.Constraints = make(map[string]interface{})
-	for _, cap := range delegation.Capabilities {
-		for k, v := range cap.Nb {
+	// UCAN 1.0 tokens name a command on a subject instead of capabilities
+	if env := delegation.Envelope; env != nil {
+		analysis.PrimaryAction = env.Command
+		analysis.TargetResource = env.Subject
+		if env.Command != "" {
+			analysis.RequiredPermissions = append(analysis.RequiredPermissions, env.Command)
+		}
+	}
+
+	if found != nil {
+		analysis.TaskType = "invocation"
+		analysis.Source = found.source
+		analysis.HasInvokeCapability = len(analysis.RequiredPermissions) > 0
+		analysis.InvokePatterns = append(analysis.InvokePatterns, analysis.RequiredPermissions...)
+	}
+
+	// UCAN 1.0 invocations carry their arguments in args, ucanto ones in nb
+	if delegation.Envelope != nil {
+		for k, v := range delegation.Envelope.Args {
 			analysis.Constraints[k] = v
 		}
+	} else {
+		for _, cap := range delegation.Capabilities {
+			for k, v := range cap.Nb {
+				analysis.Constraints[k] = v
+			}
+		}
 	}
 
 	return analysis
 }
 
 // Comprehensive capability analysis
-func (s *Service) analyzeCapabilities(capabilities []models.CapabilityInfo) *models.CapabilityAnalysis {
+func (s *Service) analyzeCapabilities(capabilities []models.CapabilityInfo, invoked bool) *models.CapabilityAnalysis {
 	analysis := &models.CapabilityAnalysis{
 		Categories:     make(map[string][]models.CapabilityInfo),
 		TotalCount:     len(capabilities),
@@ -314,7 +465,7 @@ func (s *Service) analyzeCapabilities(capabilities []models.CapabilityInfo) *mod
 		analysis.Categories[category] = append(analysis.Categories[category], cap)
 
 		// Count types
-		if s.isInvokeCapability(cap.Can) {
+		if invoked {
 			analysis.InvokeCount++
 		} else {
 			analysis.DelegateCount++
@@ -333,21 +484,6 @@ func (s *Service) analyzeCapabilities(capabilities []models.CapabilityInfo) *mod
 }
 
 // Helper functions
-func (s *Service) isInvokeCapability(capability string) bool {
-	invokePatterns := []string{"invoke", "execute", "run", "call", "perform"}
-	
-	for _, pattern := range invokePatterns {
-		if cap := capability; len(cap) >= len(pattern) {
-			for i := 0; i <= len(cap)-len(pattern); i++ {
-				if cap[i:i+len(pattern)] == pattern {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-
 func (s *Service) categorizeCapability(capability string) string {
 	switch {
 	case len(capability) >= 5 && capability[:5] == "store":
@@ -356,8 +492,6 @@ func (s *Service) categorizeCapability(capability string) string {
 		return "space"
 	case len(capability) >= 6 && capability[:6] == "upload":
 		return "upload"
-	case s.isInvokeCapability(capability):
-		return "invocation"
 	case len(capability) >= 4 && capability[:4] == "blob":
         return "blob"
     case len(capability) >= 5 && capability[:5] == "index":
@@ -369,46 +503,8 @@ func (s *Service) categorizeCapability(capability string) string {
 
 // extractCaveats converts IPLD node to map
 func (s *Service) extractCaveats(nb any) map[string]interface{} {
-    result := make(map[string]interface{})
-    if nb == nil {
-        return result
-    }
-
-    if node, ok := nb.(ipld.Node); ok && node.Kind() == ipld.Kind_Map {
-        iter := node.MapIterator()
-        for !iter.Done() {
-            k, v, err := iter.Next()
-            if err != nil {
-                break
-            }
-            if keyStr, err := k.AsString(); err == nil {
-                result[keyStr] = s.nodeToValue(v)
-            }
-        }
-    }
-
-    return result
+	if node, ok := nb.(ipld.Node); ok {
+		return utils.NodeToMap(node)
+	}
+	return make(map[string]interface{})
 }
-
-// nodeToValue converts IPLD node to Go value
-func (s *Service) nodeToValue(node ipld.Node) interface{} {
-    switch node.Kind() {
-    case ipld.Kind_Bool:
-        v, _ := node.AsBool()
-        return v
-    case ipld.Kind_Int:
-        v, _ := node.AsInt()
-        return v
-    case ipld.Kind_Float:
-        v, _ := node.AsFloat()
-        return v
-    case ipld.Kind_String:
-        v, _ := node.AsString()
-        return v
-    case ipld.Kind_Bytes:
-        v, _ := node.AsBytes()
-        return v
-    default:
-        return nil
-    }
-}
\ No newline at end of file
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// UCAN spec versions
+const (
+	SpecVersion08  = "0.8"
+	SpecVersion09  = "0.9"
+	SpecVersion010 = "0.10"
+	SpecVersion1RC = "1.0-rc"
+)
+
+// specVersion reduces a version string such as "0.9.1" or "1.0.0-rc.1" to
+// the spec version it implements, or "" when it is not recognisable
+func specVersion(version string) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if strings.HasPrefix(version, "1.0.0-rc") {
+		return SpecVersion1RC
+	}
+
+	major, rest, found := strings.Cut(version, ".")
+	if !found || major == "" {
+		return ""
+	}
+	minor, _, _ := strings.Cut(rest, ".")
+	if minor == "" {
+		return ""
+	}
+	return major + "." + minor
+}
+
+// jwtHeader picks the UCAN fields out of a decoded JWT header
+func jwtHeader(header map[string]interface{}) *models.JWTHeader {
+	if header == nil {
+		return nil
+	}
+	alg, _ := header["alg"].(string)
+	typ, _ := header["typ"].(string)
+	ucv, _ := header["ucv"].(string)
+	return &models.JWTHeader{Alg: alg, Typ: typ, Ucv: ucv}
+}
+
+// rawTokenVersion works out the spec version of a raw token from its
+// payload tag (1.0), its declared ucv or v (0.x) or, failing those, the
+// shape of its claims. inferred is true in the last case.
+func rawTokenVersion(parsed *utils.ParsedJWT) (version string, inferred bool) {
+	if parsed.Envelope != "" {
+		_, tagVersion, _ := strings.Cut(parsed.Envelope, "@")
+		return specVersion(tagVersion), false
+	}
+
+	// 0.8 and 0.9 declare ucv in the JWT header, 0.10 in the payload and
+	// ucanto CBOR blocks as v
+	declared := parsed.Claims.Version
+	if ucv, ok := parsed.Header["ucv"].(string); ok && ucv != "" {
+		declared = ucv
+	}
+	if version := specVersion(declared); version != "" {
+		return version, false
+	}
+
+	switch {
+	case parsed.Claims.AttShape == "map":
+		return SpecVersion010, true
+	case hasInlineProof(parsed.Claims.Proofs):
+		return SpecVersion08, true
+	case parsed.Claims.AttShape == "list":
+		return SpecVersion09, true
+	default:
+		return "", true
+	}
+}
+
+func hasInlineProof(proofs []string) bool {
+	for _, proof := range proofs {
+		if utils.IsJWT(proof) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+)
+
+// CheckAttenuation reports whether a capability held in a proof covers a
+// capability claimed by the delegation citing that proof
+func CheckAttenuation(parent, child models.CapabilityInfo) models.AttenuationCheck {
+	check := models.AttenuationCheck{
+		ResourceMatch:       ResourceCovers(parent.With, child.With),
+		AbilityMatch:        AbilityCovers(parent.Can, child.Can),
+		CaveatProperlyAdded: CaveatsNarrow(parent.Nb, child.Nb),
+	}
+
+	if !check.ResourceMatch {
+		check.Issues = append(check.Issues, fmt.Sprintf("resource %s is not within %s", child.With, parent.With))
+	}
+	if !check.AbilityMatch {
+		check.Issues = append(check.Issues, fmt.Sprintf("ability %s is not covered by %s", child.Can, parent.Can))
+	}
+	if !check.CaveatProperlyAdded {
+		check.Issues = append(check.Issues, fmt.Sprintf("caveats on %s loosen those of the proof", child.Can))
+	}
+
+	check.Valid = check.ResourceMatch && check.AbilityMatch && check.CaveatProperlyAdded
+	return check
+}
+
+// ResourceCovers reports whether parent resource contains child resource.
+// A trailing "*" matches any suffix and "ucan:*" matches every resource.
+func ResourceCovers(parent, child string) bool {
+	if parent == child || parent == "ucan:*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(parent, "*"); ok {
+		return strings.HasPrefix(child, prefix)
+	}
+	return false
+}
+
+// AbilityCovers reports whether parent ability implies child ability.
+// "*" implies everything and "ns/*" implies every ability under "ns/".
+func AbilityCovers(parent, child string) bool {
+	if parent == child || parent == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(parent, "/*"); ok {
+		return strings.HasPrefix(child, prefix+"/")
+	}
+	return false
+}
+
+// limitCaveats are the caveat keys holding an upper limit rather than an
+// exact value, which a child may lower. Any other number, such as the blob
+// size of space/blob/add, must stay as the proof has it.
+var limitCaveats = map[string]bool{
+	"limit":    true,
+	"max":      true,
+	"maxSize":  true,
+	"maxCount": true,
+}
+
+// CaveatsNarrow reports whether child caveats keep every constraint of the
+// parent caveats. The child may add keys; shared keys must hold equal
+// values, recursing into nested maps, except that numbers under a key in
+// limitCaveats may be lowered.
+func CaveatsNarrow(parent, child interface{}) bool {
+	parentMap, ok := parent.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(parent, child)
+	}
+
+	childMap, ok := child.(map[string]interface{})
+	if !ok {
+		return len(parentMap) == 0
+	}
+
+	for key, parentVal := range parentMap {
+		childVal, exists := childMap[key]
+		if !exists {
+			return false
+		}
+		if limitCaveats[key] {
+			if narrows, ok := numberNarrows(parentVal, childVal); ok {
+				if !narrows {
+					return false
+				}
+				continue
+			}
+		}
+		if !CaveatsNarrow(parentVal, childVal) {
+			return false
+		}
+	}
+	return true
+}
+
+// numberNarrows reports whether child is at most parent when both are
+// numbers, as decoded from DAG-CBOR (int64, float64) or JWT JSON (float64)
+func numberNarrows(parent, child interface{}) (narrows, ok bool) {
+	if p, ok := parent.(int64); ok {
+		if c, ok := child.(int64); ok {
+			return c <= p, true
+		}
+	}
+
+	p, ok := caveatNumber(parent)
+	if !ok {
+		return false, false
+	}
+	c, ok := caveatNumber(child)
+	if !ok {
+		return false, false
+	}
+	return c <= p, true
+}
+
+func caveatNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// checkProofAttenuation verifies each capability of del against its resolved
+// proofs. It returns link-level issues for capabilities no proof grants and
+// a per-proof breakdown of the checks.
+func (s *Service) checkProofAttenuation(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse) ([]models.ValidationIssue, []models.ProofValidation) {
+	var resolved []*models.DelegationResponse
+	for _, proof := range del.Proofs {
+		if proofDel, ok := byCID[proof.CID]; ok {
+			resolved = append(resolved, proofDel)
+		}
+	}
+
+	// Root delegations have nothing to attenuate against
+	if len(resolved) == 0 {
+		return nil, nil
+	}
+
+	// Capabilities on the issuer's own resource need no proof, so when every
+	// capability is one the proofs have nothing to be checked against
+	covered := make([]bool, len(del.Capabilities))
+	needsProof := false
+	for i, cap := range del.Capabilities {
+		covered[i] = cap.With == del.Issuer
+		needsProof = needsProof || !covered[i]
+	}
+	if !needsProof {
+		return nil, nil
+	}
+
+	var validations []models.ProofValidation
+	for _, proofDel := range resolved {
+		attenuation := models.AttenuationCheck{
+			ResourceMatch:       true,
+			AbilityMatch:        true,
+			CaveatProperlyAdded: true,
+		}
+		considered := 0
+
+		for i, cap := range del.Capabilities {
+			if cap.With == del.Issuer {
+				continue
+			}
+
+			check := bestAttenuationMatch(proofDel.Capabilities, cap)
+			if !check.ResourceMatch {
+				continue
+			}
+
+			considered++
+			if check.Valid {
+				covered[i] = true
+				continue
+			}
+			attenuation.AbilityMatch = attenuation.AbilityMatch && check.AbilityMatch
+			attenuation.CaveatProperlyAdded = attenuation.CaveatProperlyAdded && check.CaveatProperlyAdded
+			attenuation.Issues = append(attenuation.Issues, check.Issues...)
+		}
+
+		if considered == 0 {
+			attenuation = models.AttenuationCheck{
+				Issues: []string{"proof grants none of the delegated resources"},
+			}
+		}
+		attenuation.Valid = attenuation.ResourceMatch && attenuation.AbilityMatch && attenuation.CaveatProperlyAdded
+
+		validations = append(validations, models.ProofValidation{
+			ProofCID:     proofDel.CID,
+			Valid:        attenuation.Valid,
+			Capabilities: proofDel.Capabilities,
+			Attenuation:  attenuation,
+		})
+	}
+
+	var issues []models.ValidationIssue
+	for i, cap := range del.Capabilities {
+		if covered[i] {
+			continue
+		}
+		issues = append(issues, models.ValidationIssue{
+			Type:     "attenuation_violation",
+			Message:  fmt.Sprintf("Capability %s on %s is not granted by any proof", cap.Can, cap.With),
+			Severity: "error",
+			Context: map[string]interface{}{
+				"can":  cap.Can,
+				"with": cap.With,
+			},
+		})
+	}
+
+	return issues, validations
+}
+
+// bestAttenuationMatch returns the check against the proof capability that
+// comes closest to covering cap, preferring full matches
+func bestAttenuationMatch(proofCaps []models.CapabilityInfo, cap models.CapabilityInfo) models.AttenuationCheck {
+	var best models.AttenuationCheck
+	bestScore := -1
+
+	for _, parent := range proofCaps {
+		check := CheckAttenuation(parent, cap)
+		if check.Valid {
+			return check
+		}
+
+		score := 0
+		if check.ResourceMatch {
+			score += 4
+		}
+		if check.AbilityMatch {
+			score += 2
+		}
+		if check.CaveatProperlyAdded {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = check, score
+		}
+	}
+
+	return best
+}
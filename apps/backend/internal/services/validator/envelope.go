@@ -0,0 +1,145 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/parser"
+)
+
+// CommandCovers reports whether a UCAN 1.0 command delegated in a proof
+// covers the command of the token citing it. Commands are slash-separated
+// paths and delegating a command delegates every command below it, so "/"
+// covers everything and "/blob" covers "/blob/add".
+func CommandCovers(parent, child string) bool {
+	if parent == child || parent == "/" {
+		return true
+	}
+	return strings.HasPrefix(child, strings.TrimSuffix(parent, "/")+"/")
+}
+
+// SubjectCovers reports whether a proof for parent subject also applies to
+// child subject. A powerline proof (null subject) applies to any subject.
+func SubjectCovers(parent *models.Envelope, child *models.Envelope) bool {
+	return parent.Powerline || parent.Subject == child.Subject
+}
+
+// checkEnvelope applies the checks that replace capability checks for UCAN
+// 1.0 tokens: a command and a well-formed policy must be present and, for
+// invocations, the resolved proofs must form a chain from the subject to the
+// invoker whose policies all accept the invocation args
+func (s *Service) checkEnvelope(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse) []models.ValidationIssue {
+	env := del.Envelope
+	var issues []models.ValidationIssue
+
+	if env.Command == "" {
+		issues = append(issues, models.ValidationIssue{
+			Type:     "missing_command",
+			Message:  fmt.Sprintf("UCAN %s %s has no command", env.Version, env.Kind),
+			Severity: "error",
+		})
+	}
+	if env.Header.Error != "" {
+		issues = append(issues, models.ValidationIssue{
+			Type:     "invalid_varsig_header",
+			Message:  fmt.Sprintf("Varsig header could not be decoded: %s", env.Header.Error),
+			Severity: "warning",
+		})
+	}
+	issues = append(issues, s.checkPolicySyntax(env)...)
+
+	// 1.0 invocations list their delegations in order, from the one issued by
+	// the subject down to the one delegating to the invoker
+	var chain []*models.DelegationResponse
+	for _, proof := range del.Proofs {
+		proofDel, ok := byCID[proof.CID]
+		if !ok {
+			// Reported as missing_proof; alignment cannot be checked across the gap
+			return issues
+		}
+		if proofDel.Envelope == nil || proofDel.Envelope.Kind != parser.EnvelopeDelegation {
+			issues = append(issues, models.ValidationIssue{
+				Type:     "invalid_proof",
+				Message:  fmt.Sprintf("Proof %d (%s) is not a UCAN 1.0 delegation", proof.Index, proof.CID),
+				Severity: "error",
+				Context: map[string]interface{}{
+					"proofCid":   proof.CID,
+					"proofIndex": proof.Index,
+				},
+			})
+			return issues
+		}
+		chain = append(chain, proofDel)
+	}
+	if len(chain) == 0 {
+		return issues
+	}
+
+	if first := chain[0]; !first.Envelope.Powerline && first.Issuer != first.Envelope.Subject {
+		issues = append(issues, models.ValidationIssue{
+			Type:     "principal_misaligned",
+			Message:  fmt.Sprintf("First proof %s is issued by %s, not by its subject %s", first.CID, first.Issuer, first.Envelope.Subject),
+			Severity: "error",
+			Context: map[string]interface{}{
+				"proofCid": first.CID,
+				"issuer":   first.Issuer,
+				"subject":  first.Envelope.Subject,
+			},
+		})
+	}
+
+	for i, proofDel := range chain {
+		// Each delegation must be addressed to the issuer of the next one
+		nextIssuer := del.Issuer
+		if i+1 < len(chain) {
+			nextIssuer = chain[i+1].Issuer
+		}
+		if proofDel.Audience != nextIssuer {
+			issues = append(issues, models.ValidationIssue{
+				Type: "principal_misaligned",
+				Message: fmt.Sprintf("Proof %d (%s) is delegated to %s but the next link is issued by %s",
+					i, proofDel.CID, proofDel.Audience, nextIssuer),
+				Severity: "error",
+				Context: map[string]interface{}{
+					"proofCid":      proofDel.CID,
+					"proofIndex":    i,
+					"proofAudience": proofDel.Audience,
+					"nextIssuer":    nextIssuer,
+				},
+			})
+		}
+
+		if !CommandCovers(proofDel.Envelope.Command, env.Command) {
+			issues = append(issues, models.ValidationIssue{
+				Type:     "command_not_delegated",
+				Message:  fmt.Sprintf("Command %s is not within %s delegated by proof %s", env.Command, proofDel.Envelope.Command, proofDel.CID),
+				Severity: "error",
+				Context: map[string]interface{}{
+					"proofCid":     proofDel.CID,
+					"command":      env.Command,
+					"proofCommand": proofDel.Envelope.Command,
+				},
+			})
+		}
+
+		if !SubjectCovers(proofDel.Envelope, env) {
+			issues = append(issues, models.ValidationIssue{
+				Type:     "subject_mismatch",
+				Message:  fmt.Sprintf("Subject %s differs from %s in proof %s", env.Subject, proofDel.Envelope.Subject, proofDel.CID),
+				Severity: "error",
+				Context: map[string]interface{}{
+					"proofCid":     proofDel.CID,
+					"subject":      env.Subject,
+					"proofSubject": proofDel.Envelope.Subject,
+				},
+			})
+		}
+	}
+
+	if env.Kind == parser.EnvelopeInvocation {
+		issues = append(issues, s.checkPolicies(del, chain)...)
+	}
+
+	return issues
+}
@@ -0,0 +1,649 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
+)
+
+// Policy is a compiled UCAN 1.0 policy: a list of statements that must all
+// hold for the arguments of an invocation
+type Policy []Statement
+
+// Statement is one compiled policy statement
+type Statement struct {
+	Op       string
+	Selector *Selector   // comparisons, like and quantifiers
+	Value    interface{} // comparisons and like
+	Children []Statement // and/or take many, not/all/any exactly one
+	Raw      interface{} // the statement as written in the policy
+	Path     string      // position in the policy, e.g. "pol[1][1][0]"
+
+	pattern *regexp.Regexp // like
+}
+
+// PolicyFailure describes the statement that rejected a set of arguments
+type PolicyFailure struct {
+	Statement interface{} // the statement as written
+	Path      string      // its position in the policy
+	Selector  string      // selector applied to the invocation args
+	Value     interface{} // value it selected, nil when it selected nothing
+	Reason    string
+}
+
+// CompilePolicy parses a decoded `pol` field. The policy is checked as a
+// whole so malformed statements are reported even in branches that a
+// particular set of arguments would never reach.
+func CompilePolicy(policy []interface{}) (Policy, error) {
+	compiled := make(Policy, 0, len(policy))
+	for i, raw := range policy {
+		stmt, err := compileStatement(raw, fmt.Sprintf("pol[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, stmt)
+	}
+	return compiled, nil
+}
+
+// Match evaluates the policy against invocation args and returns the first
+// failing statement, or nil when every statement holds
+func (p Policy) Match(args map[string]interface{}) *PolicyFailure {
+	var root interface{} = args
+	if args == nil {
+		root = map[string]interface{}{}
+	}
+	for _, stmt := range p {
+		if failure := stmt.match(root, ""); failure != nil {
+			return failure
+		}
+	}
+	return nil
+}
+
+func compileStatement(raw interface{}, path string) (Statement, error) {
+	list, ok := raw.([]interface{})
+	if !ok || len(list) == 0 {
+		return Statement{}, fmt.Errorf("%s: statement must be a non-empty list", path)
+	}
+	op, ok := list[0].(string)
+	if !ok {
+		return Statement{}, fmt.Errorf("%s: operator must be a string", path)
+	}
+
+	stmt := Statement{Op: op, Raw: raw, Path: path}
+	arity := func(n int) error {
+		if len(list) != n {
+			return fmt.Errorf("%s: %q takes %d operands, got %d", path, op, n-1, len(list)-1)
+		}
+		return nil
+	}
+	selector := func() error {
+		str, ok := list[1].(string)
+		if !ok {
+			return fmt.Errorf("%s: selector must be a string", path)
+		}
+		sel, err := ParseSelector(str)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		stmt.Selector = sel
+		return nil
+	}
+
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "like":
+		if err := arity(3); err != nil {
+			return stmt, err
+		}
+		if err := selector(); err != nil {
+			return stmt, err
+		}
+		stmt.Value = list[2]
+
+		switch op {
+		case "<", "<=", ">", ">=":
+			if _, ok := toNumber(stmt.Value); !ok {
+				return stmt, fmt.Errorf("%s: %q needs a number, got %v", path, op, stmt.Value)
+			}
+		case "like":
+			pattern, ok := stmt.Value.(string)
+			if !ok {
+				return stmt, fmt.Errorf("%s: like needs a string pattern", path)
+			}
+			stmt.pattern = globToRegexp(pattern)
+		}
+
+	case "not":
+		if err := arity(2); err != nil {
+			return stmt, err
+		}
+		child, err := compileStatement(list[1], path+"[1]")
+		if err != nil {
+			return stmt, err
+		}
+		stmt.Children = []Statement{child}
+
+	case "and", "or":
+		if err := arity(2); err != nil {
+			return stmt, err
+		}
+		children, ok := list[1].([]interface{})
+		if !ok {
+			return stmt, fmt.Errorf("%s: %q needs a list of statements", path, op)
+		}
+		for i, childRaw := range children {
+			child, err := compileStatement(childRaw, fmt.Sprintf("%s[1][%d]", path, i))
+			if err != nil {
+				return stmt, err
+			}
+			stmt.Children = append(stmt.Children, child)
+		}
+
+	case "all", "any":
+		if err := arity(3); err != nil {
+			return stmt, err
+		}
+		if err := selector(); err != nil {
+			return stmt, err
+		}
+		child, err := compileStatement(list[2], path+"[2]")
+		if err != nil {
+			return stmt, err
+		}
+		stmt.Children = []Statement{child}
+
+	default:
+		return stmt, fmt.Errorf("%s: unknown operator %q", path, op)
+	}
+
+	return stmt, nil
+}
+
+// match evaluates the statement against value. prefix is the selector that
+// led to value, so failures inside quantifiers name the full path.
+func (st Statement) match(value interface{}, prefix string) *PolicyFailure {
+	fail := func(selector string, selected interface{}, reason string) *PolicyFailure {
+		return &PolicyFailure{
+			Statement: st.Raw,
+			Path:      st.Path,
+			Selector:  selector,
+			Value:     selected,
+			Reason:    reason,
+		}
+	}
+
+	switch st.Op {
+	case "and":
+		for _, child := range st.Children {
+			if failure := child.match(value, prefix); failure != nil {
+				return failure
+			}
+		}
+		return nil
+
+	case "or":
+		// An empty "or" holds, as the spec defines it
+		if len(st.Children) == 0 {
+			return nil
+		}
+		for _, child := range st.Children {
+			if child.match(value, prefix) == nil {
+				return nil
+			}
+		}
+		return fail("", nil, "no alternative holds")
+
+	case "not":
+		if st.Children[0].match(value, prefix) == nil {
+			return fail("", nil, "negated statement holds")
+		}
+		return nil
+	}
+
+	selectorPath := joinSelector(prefix, st.Selector.Raw)
+	selected, err := st.Selector.Select(value)
+	if err != nil {
+		return fail(selectorPath, nil, err.Error())
+	}
+
+	switch st.Op {
+	case "==":
+		if !valuesEqual(selected, st.Value) {
+			return fail(selectorPath, selected, fmt.Sprintf("%s is not equal to %s", formatValue(selected), formatValue(st.Value)))
+		}
+	case "!=":
+		if valuesEqual(selected, st.Value) {
+			return fail(selectorPath, selected, fmt.Sprintf("%s is equal to %s", formatValue(selected), formatValue(st.Value)))
+		}
+	case "<", "<=", ">", ">=":
+		got, ok := toNumber(selected)
+		if !ok {
+			return fail(selectorPath, selected, fmt.Sprintf("%s is not a number", formatValue(selected)))
+		}
+		want, _ := toNumber(st.Value)
+		if !compareNumbers(st.Op, got, want) {
+			return fail(selectorPath, selected, fmt.Sprintf("%s is not %s %s", formatValue(selected), st.Op, formatValue(st.Value)))
+		}
+	case "like":
+		str, ok := selected.(string)
+		if !ok {
+			return fail(selectorPath, selected, fmt.Sprintf("%s is not a string", formatValue(selected)))
+		}
+		if !st.pattern.MatchString(str) {
+			return fail(selectorPath, selected, fmt.Sprintf("%q does not match %q", str, st.Value))
+		}
+	case "all", "any":
+		return st.quantify(selected, selectorPath, fail)
+	}
+
+	return nil
+}
+
+// quantify applies the child statement of all/any to every element of a
+// list or every value of a map
+func (st Statement) quantify(selected interface{}, selectorPath string, fail func(string, interface{}, string) *PolicyFailure) *PolicyFailure {
+	type element struct {
+		value  interface{}
+		prefix string
+	}
+
+	var elements []element
+	switch coll := selected.(type) {
+	case []interface{}:
+		for i, v := range coll {
+			elements = append(elements, element{v, fmt.Sprintf("%s[%d]", selectorPath, i)})
+		}
+	case map[string]interface{}:
+		for _, k := range slices.Sorted(maps.Keys(coll)) {
+			elements = append(elements, element{coll[k], fmt.Sprintf("%s[%q]", selectorPath, k)})
+		}
+	default:
+		return fail(selectorPath, selected, fmt.Sprintf("%s is not a list or map", formatValue(selected)))
+	}
+
+	child := st.Children[0]
+	for _, el := range elements {
+		failure := child.match(el.value, el.prefix)
+		if st.Op == "all" && failure != nil {
+			return failure
+		}
+		if st.Op == "any" && failure == nil {
+			return nil
+		}
+	}
+
+	if st.Op == "any" {
+		return fail(selectorPath, selected, "no element satisfies the statement")
+	}
+	return nil
+}
+
+// checkPolicies evaluates the args of a UCAN 1.0 invocation against the
+// policy of every delegation in its chain
+func (s *Service) checkPolicies(inv *models.DelegationResponse, chain []*models.DelegationResponse) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	for _, proofDel := range chain {
+		if len(proofDel.Envelope.Policy) == 0 {
+			continue
+		}
+
+		policy, err := CompilePolicy(proofDel.Envelope.Policy)
+		if err != nil {
+			// Reported on the delegation itself
+			continue
+		}
+
+		failure := policy.Match(inv.Envelope.Args)
+		if failure == nil {
+			continue
+		}
+
+		issues = append(issues, models.ValidationIssue{
+			Type: "policy_violation",
+			Message: fmt.Sprintf("Arguments fail policy statement %s of proof %s: %s",
+				formatValue(failure.Statement), proofDel.CID, failure.Reason),
+			Severity: "error",
+			Context: map[string]interface{}{
+				"proofCid":  proofDel.CID,
+				"statement": failure.Statement,
+				"path":      failure.Path,
+				"selector":  failure.Selector,
+				"value":     failure.Value,
+				"reason":    failure.Reason,
+			},
+		})
+	}
+
+	return issues
+}
+
+// checkPolicySyntax reports a policy that cannot be compiled
+func (s *Service) checkPolicySyntax(env *models.Envelope) []models.ValidationIssue {
+	if len(env.Policy) == 0 {
+		return nil
+	}
+	if _, err := CompilePolicy(env.Policy); err != nil {
+		return []models.ValidationIssue{{
+			Type:     "invalid_policy",
+			Message:  fmt.Sprintf("Policy cannot be evaluated: %v", err),
+			Severity: "error",
+		}}
+	}
+	return nil
+}
+
+// joinSelector appends a selector evaluated relative to an element to the
+// selector of that element: ".items[0]" and ".size" give ".items[0].size"
+func joinSelector(prefix, selector string) string {
+	if prefix == "" {
+		return selector
+	}
+	if selector == "." {
+		return prefix
+	}
+	if strings.HasPrefix(selector, ".[") {
+		return prefix + selector[1:]
+	}
+	return prefix + selector
+}
+
+// globToRegexp compiles a like pattern: "*" matches any run of characters
+// and "\*" a literal star
+func globToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`(?s)^`)
+	var literal strings.Builder
+	flush := func() {
+		b.WriteString(regexp.QuoteMeta(literal.String()))
+		literal.Reset()
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern) && pattern[i+1] == '*':
+			literal.WriteByte('*')
+			i++
+		case pattern[i] == '*':
+			flush()
+			b.WriteString(`.*`)
+		default:
+			literal.WriteByte(pattern[i])
+		}
+	}
+	flush()
+	b.WriteString(`$`)
+	return regexp.MustCompile(b.String())
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func compareNumbers(op string, got, want float64) bool {
+	switch op {
+	case "<":
+		return got < want
+	case "<=":
+		return got <= want
+	case ">":
+		return got > want
+	default:
+		return got >= want
+	}
+}
+
+// valuesEqual compares decoded IPLD values, treating integers and floats
+// of the same magnitude as equal
+func valuesEqual(a, b interface{}) bool {
+	if an, ok := toNumber(a); ok {
+		bn, ok := toNumber(b)
+		return ok && an == bn
+	}
+
+	switch av := a.(type) {
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !valuesEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			other, exists := bv[k]
+			if !exists || !valuesEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	case utils.BytesValue:
+		bv, ok := b.(utils.BytesValue)
+		return ok && bytes.Equal(av, bv)
+	default:
+		return a == b
+	}
+}
+
+// formatValue renders a value the way it appears in DAG-JSON
+func formatValue(v interface{}) string {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// Selector is a compiled jq-like policy selector such as ".foo[0]"
+type Selector struct {
+	Raw      string
+	segments []selectorSegment
+}
+
+type selectorSegment struct {
+	field    string // map key, when not an index or slice
+	index    int
+	isIndex  bool
+	isSlice  bool
+	start    *int
+	end      *int
+	optional bool // "?" suffix: select null instead of failing
+}
+
+var selectorField = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+
+// ParseSelector parses a policy selector. Supported forms are the identity
+// ".", fields ".foo", quoted keys `.["foo"]`, indexes ".[0]" and ".[-1]",
+// slices ".[1:3]", and an optional "?" suffix on any segment.
+func ParseSelector(raw string) (*Selector, error) {
+	if !strings.HasPrefix(raw, ".") {
+		return nil, fmt.Errorf("selector %q must start with \".\"", raw)
+	}
+
+	sel := &Selector{Raw: raw}
+	rest := raw
+	if rest == "." {
+		return sel, nil
+	}
+
+	for rest != "" {
+		var seg selectorSegment
+
+		switch {
+		case strings.HasPrefix(rest, ".["), strings.HasPrefix(rest, "["):
+			rest = rest[strings.Index(rest, "[")+1:]
+			closing := strings.Index(rest, "]")
+			if closing < 0 {
+				return nil, fmt.Errorf("selector %q has an unclosed bracket", raw)
+			}
+			inner := rest[:closing]
+			rest = rest[closing+1:]
+
+			var err error
+			seg, err = parseBracket(inner)
+			if err != nil {
+				return nil, fmt.Errorf("selector %q: %w", raw, err)
+			}
+		case strings.HasPrefix(rest, "."):
+			field := selectorField.FindString(rest[1:])
+			if field == "" {
+				return nil, fmt.Errorf("selector %q has an invalid field at %q", raw, rest)
+			}
+			seg.field = field
+			rest = rest[1+len(field):]
+		default:
+			return nil, fmt.Errorf("selector %q has an unexpected %q", raw, rest)
+		}
+
+		if strings.HasPrefix(rest, "?") {
+			seg.optional = true
+			rest = rest[1:]
+		}
+		sel.segments = append(sel.segments, seg)
+	}
+
+	return sel, nil
+}
+
+func parseBracket(inner string) (selectorSegment, error) {
+	var seg selectorSegment
+
+	switch {
+	case inner == "":
+		return seg, fmt.Errorf("iterators are only supported through all and any")
+	case strings.HasPrefix(inner, `"`):
+		key, err := strconv.Unquote(inner)
+		if err != nil {
+			return seg, fmt.Errorf("invalid quoted key %s", inner)
+		}
+		seg.field = key
+	case strings.Contains(inner, ":"):
+		from, to, _ := strings.Cut(inner, ":")
+		seg.isSlice = true
+		for _, bound := range []struct {
+			text string
+			dst  **int
+		}{{from, &seg.start}, {to, &seg.end}} {
+			if bound.text == "" {
+				continue
+			}
+			n, err := strconv.Atoi(bound.text)
+			if err != nil {
+				return seg, fmt.Errorf("invalid slice bound %q", bound.text)
+			}
+			*bound.dst = &n
+		}
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return seg, fmt.Errorf("invalid index %q", inner)
+		}
+		seg.index = n
+		seg.isIndex = true
+	}
+
+	return seg, nil
+}
+
+// Select applies the selector to a value. A segment that selects nothing
+// is an error unless it is optional, in which case the result is null.
+func (sel *Selector) Select(value interface{}) (interface{}, error) {
+	current := value
+	for _, seg := range sel.segments {
+		next, err := seg.apply(current)
+		if err != nil {
+			if seg.optional {
+				return nil, nil
+			}
+			return nil, err
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func (seg selectorSegment) apply(value interface{}) (interface{}, error) {
+	switch {
+	case seg.isIndex:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s", formatValue(value))
+		}
+		i := seg.index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil, fmt.Errorf("index %d is out of range for %d elements", seg.index, len(list))
+		}
+		return list[i], nil
+
+	case seg.isSlice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot slice %s", formatValue(value))
+		}
+		start, end := 0, len(list)
+		if seg.start != nil {
+			start = clampIndex(*seg.start, len(list))
+		}
+		if seg.end != nil {
+			end = clampIndex(*seg.end, len(list))
+		}
+		if start > end {
+			start = end
+		}
+		return list[start:end], nil
+
+	default:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot select field %q from %s", seg.field, formatValue(value))
+		}
+		v, exists := m[seg.field]
+		if !exists {
+			return nil, fmt.Errorf("field %q is not present", seg.field)
+		}
+		return v, nil
+	}
+}
+
+func clampIndex(i, length int) int {
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
@@ -1,28 +1,70 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/goddhi/ucan-visualizer/internal/models"
 	"github.com/goddhi/ucan-visualizer/internal/services/parser"
+	"github.com/goddhi/ucan-visualizer/pkg/utils"
 )
 
+// DefaultExpiringSoonWindow is how close to expiry a delegation gets an
+// "expiring_soon" warning when the caller does not choose a window
+const DefaultExpiringSoonWindow = 24 * time.Hour
+
 type Service struct {
 	parser *parser.Service
+	clock  func() time.Time
 }
 
 func NewService() *Service {
+	return NewServiceWithClock(time.Now)
+}
+
+// NewServiceWithClock creates a validator that reads the current time from clock
+func NewServiceWithClock(clock func() time.Time) *Service {
 	return &Service{
 		parser: parser.NewService(),
+		clock:  clock,
 	}
 }
 
-// ValidateChain validates a delegation chain
+// Options tunes a single validation run
+type Options struct {
+	// At is the evaluation time. Zero means the service clock's current time.
+	At time.Time
+	// ExpiringSoonWindow is how close to expiry triggers a warning. Zero means
+	// DefaultExpiringSoonWindow.
+	ExpiringSoonWindow time.Duration
+}
+
+// ValidateChain validates a delegation chain as of the current time
 func (s *Service) ValidateChain(tokenBytes []byte) (*models.ValidationResult, error) {
+	return s.ValidateChainWithOptions(tokenBytes, Options{})
+}
+
+// ValidateChainWithOptions validates a delegation chain as of opts.At
+func (s *Service) ValidateChainWithOptions(tokenBytes []byte, opts Options) (*models.ValidationResult, error) {
+	opts = s.withDefaults(opts)
+
 	// 1. Delegate parsing to the Parser Service
 	// Since your Parser is fixed, this now works for BOTH CAR files and Raw Tokens!
-	chain, err := s.parser.ParseDelegationChain(tokenBytes)
+	dag, err := s.parser.ParseDelegationChain(tokenBytes)
+	var mismatch *utils.IntegrityError
+	if errors.As(err, &mismatch) {
+		return &models.ValidationResult{
+			Valid: false,
+			RootCause: &models.ValidationError{
+				Type:    "block_integrity",
+				Message: fmt.Sprintf("Delegation block %s does not hash to its CID (content hashes to %s)", mismatch.CID, mismatch.Computed),
+				Link:    &models.LinkInfo{CID: mismatch.CID.String()},
+			},
+			Summary:     models.ValidationSummary{},
+			EvaluatedAt: opts.At,
+		}, nil
+	}
 	if err != nil {
 		return &models.ValidationResult{
 			Valid: false,
@@ -30,18 +72,26 @@ func (s *Service) ValidateChain(tokenBytes []byte) (*models.ValidationResult, er
 				Type:    "parse_error",
 				Message: fmt.Sprintf("Failed to parse UCAN: %v", err),
 			},
-			Summary: models.ValidationSummary{},
+			Summary:     models.ValidationSummary{},
+			EvaluatedAt: opts.At,
 		}, nil
 	}
 
-	// 2. Validate the chain links
-	var chainLinks []models.ChainLink
-	var allIssues []models.ValidationIssue
+	return s.ValidateDAG(dag, opts), nil
+}
+
+// ValidateDAG validates an already parsed delegation DAG as of opts.At
+func (s *Service) ValidateDAG(dag *models.DelegationDAG, opts Options) *models.ValidationResult {
+	opts = s.withDefaults(opts)
 
-	for _, del := range chain {
-		link := s.validateDelegation(del)
+	// 2. Validate each delegation in the proof DAG once
+	byCID := dag.Delegations
+
+	var chainLinks []models.ChainLink
+	ordered := dag.Ordered()
+	for _, del := range ordered {
+		link := s.validateDelegation(del, byCID, opts)
 		chainLinks = append(chainLinks, link)
-		allIssues = append(allIssues, link.Issues...)
 	}
 
 	// 3. Build summary
@@ -50,21 +100,61 @@ func (s *Service) ValidateChain(tokenBytes []byte) (*models.ValidationResult, er
 	// 4. Identify root cause if invalid
 	var rootCause *models.ValidationError
 	if summary.InvalidLinks > 0 {
-		rootCause = s.findRootCause(allIssues, chainLinks[0])
+		rootCause = s.findRootCause(chainLinks)
 	}
 
+	versions := chainVersions(ordered)
+
 	return &models.ValidationResult{
-		Valid:     summary.InvalidLinks == 0,
-		Chain:     chainLinks,
-		RootCause: rootCause,
-		Summary:   summary,
-	}, nil
+		Valid:          summary.InvalidLinks == 0,
+		Chain:          chainLinks,
+		Versions:       versions,
+		MixedVersions:  len(versions) > 1,
+		RootCause:      rootCause,
+		Summary:        summary,
+		ValidityWindow: s.validityWindow(dag.RootDelegation(), byCID),
+		EvaluatedAt:    opts.At,
+	}
+}
+
+// ValidateBundle validates each chain of a bundle on its own
+func (s *Service) ValidateBundle(bundle *models.Bundle, opts Options) []*models.ValidationResult {
+	results := make([]*models.ValidationResult, 0, len(bundle.Chains))
+	for _, chain := range bundle.Chains {
+		results = append(results, s.ValidateDAG(chain.DAG, opts))
+	}
+	return results
+}
+
+// withDefaults fills in the options a caller left zero
+func (s *Service) withDefaults(opts Options) Options {
+	if opts.At.IsZero() {
+		opts.At = s.clock()
+	}
+	if opts.ExpiringSoonWindow <= 0 {
+		opts.ExpiringSoonWindow = DefaultExpiringSoonWindow
+	}
+	return opts
 }
 
 // validateDelegation checks a single delegation for issues
-func (s *Service) validateDelegation(del *models.DelegationResponse) models.ChainLink {
+func (s *Service) validateDelegation(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse, opts Options) models.ChainLink {
 	var issues []models.ValidationIssue
-	now := time.Now()
+	now := opts.At
+
+	// Check 0: Blocks hash to their CIDs. Nothing else about a tampered
+	// block can be trusted, so this comes first.
+	for _, failure := range del.Integrity {
+		issues = append(issues, models.ValidationIssue{
+			Type:     "block_integrity",
+			Message:  fmt.Sprintf("Block %s does not hash to its CID (content hashes to %s)", failure.CID, failure.Computed),
+			Severity: "error",
+			Context: map[string]interface{}{
+				"cid":      failure.CID,
+				"computed": failure.Computed,
+			},
+		})
+	}
 
 	// Check 1: Expiration
 	if !del.Expiration.IsZero() {
@@ -75,7 +165,7 @@ func (s *Service) validateDelegation(del *models.DelegationResponse) models.Chai
 				Message:  fmt.Sprintf("UCAN expired %v ago", timeExpired.Round(time.Minute)),
 				Severity: "error",
 			})
-		} else if del.Expiration.Before(now.Add(24 * time.Hour)) {
+		} else if del.Expiration.Before(now.Add(opts.ExpiringSoonWindow)) {
 			timeUntilExpiry := del.Expiration.Sub(now)
 			issues = append(issues, models.ValidationIssue{
 				Type:     "expiring_soon", 
@@ -94,8 +184,8 @@ func (s *Service) validateDelegation(del *models.DelegationResponse) models.Chai
 		})
 	}
 
-	// Check 3: Capabilities
-	if len(del.Capabilities) == 0 {
+	// Check 3: Capabilities (UCAN 1.0 tokens carry a command instead)
+	if del.Envelope == nil && len(del.Capabilities) == 0 {
 		issues = append(issues, models.ValidationIssue{
 			Type:     "no_capabilities",
 			Message:  "Delegation has no capabilities",
@@ -103,9 +193,63 @@ func (s *Service) validateDelegation(del *models.DelegationResponse) models.Chai
 		})
 	}
 
-	// Check 4: Signature (Optimistic check)
-	// If the parser marked it as verified=false but valid=true (raw token), we don't error.
-	// But if we had a real signature check failure, we would add an error here.
+	// Check 4: Signature
+	// A signature we could check and that failed is fatal; one we could not
+	// check at all (e.g. non did:key issuer) is only a warning.
+	if del.Signature.Verified && !del.Signature.Valid {
+		issues = append(issues, models.ValidationIssue{
+			Type:     "invalid_signature",
+			Message:  fmt.Sprintf("Invalid %s signature from %s: %s", del.Signature.Algorithm, del.Issuer, del.Signature.Error),
+			Severity: "error",
+		})
+	} else if !del.Signature.Verified {
+		issues = append(issues, models.ValidationIssue{
+			Type:     "unverified_signature",
+			Message:  fmt.Sprintf("Signature could not be verified: %s", del.Signature.Error),
+			Severity: "warning",
+		})
+	}
+
+	// Check 5: Proofs are present. A tampered proof block is present but
+	// unreadable, and already reported by Check 0.
+	tampered := make(map[string]bool, len(del.Integrity))
+	for _, failure := range del.Integrity {
+		tampered[failure.CID] = true
+	}
+	for _, proof := range del.Proofs {
+		if !proof.Resolved && !tampered[proof.CID] {
+			issues = append(issues, models.ValidationIssue{
+				Type:     "missing_proof",
+				Message:  fmt.Sprintf("Proof %d (%s) is not included in the token", proof.Index, proof.CID),
+				Severity: "error",
+				Context: map[string]interface{}{
+					"proofCid":   proof.CID,
+					"proofIndex": proof.Index,
+				},
+			})
+		}
+	}
+
+	// Check 6 & 7: Principal alignment and attenuation against proofs
+	var proofValidation []models.ProofValidation
+	if del.Envelope != nil {
+		issues = append(issues, s.checkEnvelope(del, byCID)...)
+	} else {
+		issues = append(issues, s.checkPrincipalAlignment(del, byCID)...)
+
+		var attenuationIssues []models.ValidationIssue
+		attenuationIssues, proofValidation = s.checkProofAttenuation(del, byCID)
+		issues = append(issues, attenuationIssues...)
+	}
+
+	// Check 8: Time bounds stay within those of proofs, and the window left
+	// by all of them contains the evaluation time
+	issues = append(issues, s.checkTimeContainment(del, byCID)...)
+	issues = append(issues, s.checkValidityWindow(del, byCID, now)...)
+
+	// Check 9: Version-specific rules, and proofs from other spec versions
+	issues = append(issues, s.checkVersionRules(del)...)
+	issues = append(issues, s.checkMixedVersions(del, byCID)...)
 
 	// Determine primary capability for display
 	var capability models.CapabilityInfo
@@ -115,19 +259,58 @@ func (s *Service) validateDelegation(del *models.DelegationResponse) models.Chai
 
 	valid := s.countErrors(issues) == 0
 
+	var command, subject string
+	if del.Envelope != nil {
+		command, subject = del.Envelope.Command, del.Envelope.Subject
+	}
+
 	return models.ChainLink{
-		Level:      del.Level,
-		CID:        del.CID,
-		Issuer:     del.Issuer,
-		Audience:   del.Audience,
-		Capability: capability,
-		Expiration: del.Expiration,
-		NotBefore:  del.NotBefore,
-		Valid:      valid,
-		Issues:     issues,
+		Level:           del.Level,
+		CID:             del.CID,
+		Issuer:          del.Issuer,
+		Audience:        del.Audience,
+		Capability:      capability,
+		Command:         command,
+		Subject:         subject,
+		Version:         del.Version,
+		Expiration:      del.Expiration,
+		NotBefore:       del.NotBefore,
+		Valid:           valid,
+		Issues:          issues,
+		ProofValidation: proofValidation,
 	}
 }
 
+// checkPrincipalAlignment verifies that every resolved proof was delegated
+// to the issuer of the delegation citing it
+func (s *Service) checkPrincipalAlignment(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	for _, proof := range del.Proofs {
+		proofDel, ok := byCID[proof.CID]
+		if !ok {
+			continue
+		}
+
+		if proofDel.Audience != del.Issuer {
+			issues = append(issues, models.ValidationIssue{
+				Type: "principal_misaligned",
+				Message: fmt.Sprintf("Issuer %s is not the audience of proof %s, which was delegated to %s",
+					del.Issuer, proof.CID, proofDel.Audience),
+				Severity: "error",
+				Context: map[string]interface{}{
+					"issuer":        del.Issuer,
+					"proofCid":      proof.CID,
+					"proofIndex":    proof.Index,
+					"proofAudience": proofDel.Audience,
+				},
+			})
+		}
+	}
+
+	return issues
+}
+
 // Helper: Count severity=error issues
 func (s *Service) countErrors(issues []models.ValidationIssue) int {
 	count := 0
@@ -162,17 +345,20 @@ func (s *Service) buildSummary(links []models.ChainLink) models.ValidationSummar
 	return summary
 }
 
-// Helper: Find the first error to report as root cause
-func (s *Service) findRootCause(issues []models.ValidationIssue, firstLink models.ChainLink) *models.ValidationError {
-	for _, issue := range issues {
-		if issue.Severity == "error" {
-			return &models.ValidationError{
-				Type:    issue.Type,
-				Message: issue.Message,
-				Link: &models.LinkInfo{
-					Issuer:   firstLink.Issuer,
-					Audience: firstLink.Audience,
-				},
+// Helper: Find the first error to report as root cause, on the link it was found
+func (s *Service) findRootCause(links []models.ChainLink) *models.ValidationError {
+	for _, link := range links {
+		for _, issue := range link.Issues {
+			if issue.Severity == "error" {
+				return &models.ValidationError{
+					Type:    issue.Type,
+					Message: issue.Message,
+					Link: &models.LinkInfo{
+						CID:      link.CID,
+						Issuer:   link.Issuer,
+						Audience: link.Audience,
+					},
+				}
 			}
 		}
 	}
@@ -0,0 +1,151 @@
+package validator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+)
+
+// checkTimeContainment flags a delegation whose time bounds reach outside
+// those of a proof it cites. Such a delegation is only usable inside the
+// proof's window, whatever its own exp/nbf say.
+func (s *Service) checkTimeContainment(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	for _, proof := range del.Proofs {
+		proofDel, ok := byCID[proof.CID]
+		if !ok {
+			continue
+		}
+
+		if !proofDel.Expiration.IsZero() && (del.Expiration.IsZero() || del.Expiration.After(proofDel.Expiration)) {
+			childExp := "never"
+			if !del.Expiration.IsZero() {
+				childExp = del.Expiration.Format(time.RFC3339)
+			}
+			issues = append(issues, models.ValidationIssue{
+				Type: "outlives_proof",
+				Message: fmt.Sprintf("Delegation expires %s but proof %s expires %s",
+					childExp, proof.CID, proofDel.Expiration.Format(time.RFC3339)),
+				Severity: "warning",
+				Context: map[string]interface{}{
+					"proofCid":        proof.CID,
+					"expiration":      del.Expiration,
+					"proofExpiration": proofDel.Expiration,
+				},
+			})
+		}
+
+		if !proofDel.NotBefore.IsZero() && (del.NotBefore.IsZero() || del.NotBefore.Before(proofDel.NotBefore)) {
+			issues = append(issues, models.ValidationIssue{
+				Type: "precedes_proof",
+				Message: fmt.Sprintf("Delegation is valid before proof %s becomes valid at %s",
+					proof.CID, proofDel.NotBefore.Format(time.RFC3339)),
+				Severity: "warning",
+				Context: map[string]interface{}{
+					"proofCid":       proof.CID,
+					"notBefore":      del.NotBefore,
+					"proofNotBefore": proofDel.NotBefore,
+				},
+			})
+		}
+	}
+
+	return issues
+}
+
+// checkValidityWindow flags a delegation whose time bounds, intersected
+// with those of every proof reachable from it, leave no time at which it
+// can be used, or exclude at. Its own bounds are checked on their own, so
+// only bounds set by a proof count against at.
+func (s *Service) checkValidityWindow(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse, at time.Time) []models.ValidationIssue {
+	window := s.validityWindow(del, byCID)
+
+	if window.Empty {
+		return []models.ValidationIssue{{
+			Type: "empty_validity_window",
+			Message: fmt.Sprintf("Delegation can never be used: %s makes it valid from %s but %s expires it at %s",
+				window.NotBeforeCID, window.NotBefore.Format(time.RFC3339),
+				window.ExpirationCID, window.Expiration.Format(time.RFC3339)),
+			Severity: "error",
+			Context: map[string]interface{}{
+				"notBefore":     window.NotBefore,
+				"notBeforeCid":  window.NotBeforeCID,
+				"expiration":    window.Expiration,
+				"expirationCid": window.ExpirationCID,
+			},
+		}}
+	}
+
+	var issues []models.ValidationIssue
+	if !window.Expiration.IsZero() && window.ExpirationCID != del.CID && window.Expiration.Before(at) {
+		issues = append(issues, models.ValidationIssue{
+			Type: "outside_validity_window",
+			Message: fmt.Sprintf("Delegation expired at %s with proof %s",
+				window.Expiration.Format(time.RFC3339), window.ExpirationCID),
+			Severity: "error",
+			Context: map[string]interface{}{
+				"proofCid":   window.ExpirationCID,
+				"expiration": window.Expiration,
+			},
+		})
+	}
+	if !window.NotBefore.IsZero() && window.NotBeforeCID != del.CID && window.NotBefore.After(at) {
+		issues = append(issues, models.ValidationIssue{
+			Type: "outside_validity_window",
+			Message: fmt.Sprintf("Delegation is not valid until proof %s becomes valid at %s",
+				window.NotBeforeCID, window.NotBefore.Format(time.RFC3339)),
+			Severity: "error",
+			Context: map[string]interface{}{
+				"proofCid":  window.NotBeforeCID,
+				"notBefore": window.NotBefore,
+			},
+		})
+	}
+	return issues
+}
+
+// validityWindow intersects the time bounds of del with those of every
+// proof reachable from it
+func (s *Service) validityWindow(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse) *models.ValidityWindow {
+	window := s.intersectWindows(del, byCID, make(map[string]bool))
+	window.Empty = !window.NotBefore.IsZero() && !window.Expiration.IsZero() &&
+		!window.NotBefore.Before(window.Expiration)
+	return &window
+}
+
+func (s *Service) intersectWindows(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse, visiting map[string]bool) models.ValidityWindow {
+	window := models.ValidityWindow{
+		NotBefore:  del.NotBefore,
+		Expiration: del.Expiration,
+	}
+	if !del.NotBefore.IsZero() {
+		window.NotBeforeCID = del.CID
+	}
+	if !del.Expiration.IsZero() {
+		window.ExpirationCID = del.CID
+	}
+
+	visiting[del.CID] = true
+	defer delete(visiting, del.CID)
+
+	for _, proof := range del.Proofs {
+		proofDel, ok := byCID[proof.CID]
+		if !ok || visiting[proof.CID] {
+			continue
+		}
+
+		proofWindow := s.intersectWindows(proofDel, byCID, visiting)
+		if proofWindow.NotBefore.After(window.NotBefore) {
+			window.NotBefore = proofWindow.NotBefore
+			window.NotBeforeCID = proofWindow.NotBeforeCID
+		}
+		if !proofWindow.Expiration.IsZero() && (window.Expiration.IsZero() || proofWindow.Expiration.Before(window.Expiration)) {
+			window.Expiration = proofWindow.Expiration
+			window.ExpirationCID = proofWindow.ExpirationCID
+		}
+	}
+
+	return window
+}
@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/parser"
+)
+
+// checkVersionRules applies the rules that differ between UCAN spec
+// versions to a single token
+func (s *Service) checkVersionRules(del *models.DelegationResponse) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	if del.Header != nil && del.VersionInferred {
+		message := "JWT declares no ucv"
+		if del.Version != "" {
+			message += fmt.Sprintf("; its shape suggests UCAN %s", del.Version)
+		}
+		issues = append(issues, models.ValidationIssue{
+			Type:     "undeclared_version",
+			Message:  message,
+			Severity: "warning",
+		})
+	}
+
+	if del.Header != nil && !strings.EqualFold(del.Header.Typ, "JWT") {
+		issues = append(issues, models.ValidationIssue{
+			Type:     "invalid_jwt_header",
+			Message:  fmt.Sprintf("JWT header typ is %q, expected \"JWT\"", del.Header.Typ),
+			Severity: "warning",
+		})
+	}
+
+	// A declared version fixes the att shape: a list up to 0.9, a map from 0.10
+	if !del.VersionInferred && del.AttShape != "" {
+		expected := ""
+		switch del.Version {
+		case parser.SpecVersion08, parser.SpecVersion09:
+			expected = "list"
+		case parser.SpecVersion010:
+			expected = "map"
+		}
+		if expected != "" && del.AttShape != expected {
+			issues = append(issues, models.ValidationIssue{
+				Type:     "att_shape_mismatch",
+				Message:  fmt.Sprintf("UCAN %s requires att as a %s, found a %s", del.Version, expected, del.AttShape),
+				Severity: "error",
+				Context: map[string]interface{}{
+					"version":  del.Version,
+					"expected": expected,
+					"found":    del.AttShape,
+				},
+			})
+		}
+	}
+
+	// Only 0.8 embeds proofs; later versions cite them by CID
+	if del.Version != "" && del.Version != parser.SpecVersion08 {
+		for _, proof := range del.Proofs {
+			if proof.Inline {
+				issues = append(issues, models.ValidationIssue{
+					Type:     "inline_proof",
+					Message:  fmt.Sprintf("Proof %d is an inline JWT, which UCAN %s replaces with a CID", proof.Index, del.Version),
+					Severity: "warning",
+					Context: map[string]interface{}{
+						"proofCid":   proof.CID,
+						"proofIndex": proof.Index,
+					},
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// checkMixedVersions flags proofs written against a different spec version
+// than the delegation citing them
+func (s *Service) checkMixedVersions(del *models.DelegationResponse, byCID map[string]*models.DelegationResponse) []models.ValidationIssue {
+	var issues []models.ValidationIssue
+
+	for _, proof := range del.Proofs {
+		proofDel, ok := byCID[proof.CID]
+		if !ok || del.Version == "" || proofDel.Version == "" || proofDel.Version == del.Version {
+			continue
+		}
+
+		issues = append(issues, models.ValidationIssue{
+			Type:     "mixed_versions",
+			Message:  fmt.Sprintf("UCAN %s delegation cites proof %s written for UCAN %s", del.Version, proof.CID, proofDel.Version),
+			Severity: "warning",
+			Context: map[string]interface{}{
+				"version":      del.Version,
+				"proofCid":     proof.CID,
+				"proofVersion": proofDel.Version,
+			},
+		})
+	}
+
+	return issues
+}
+
+// chainVersions lists the spec versions found along the chain, in order
+func chainVersions(chain []*models.DelegationResponse) []string {
+	var versions []string
+	seen := make(map[string]bool)
+	for _, del := range chain {
+		if del.Version == "" || seen[del.Version] {
+			continue
+		}
+		seen[del.Version] = true
+		versions = append(versions, del.Version)
+	}
+	return versions
+}
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+)
+
+// carV2PragmaSize is the length of the fixed CARv2 pragma, followed by a
+// 40 byte header locating the CARv1 payload
+const carV2PragmaSize = 11
+
+// carV2Pragma is the header, declaring version 2, every CARv2 starts with
+var carV2Pragma = []byte{0x0a, 0xa1, 0x67, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x02}
+
+// CARBlock is one section of a CAR as stored on disk. The CID is the one
+// the archive claims for the data; nothing checks that they agree.
+type CARBlock struct {
+	CID    cid.Cid
+	Data   []byte
+	Offset uint64 // byte offset of the section within the CAR
+}
+
+// CARFile is a decoded CAR
+type CARFile struct {
+	Version uint64
+	Roots   []cid.Cid
+	Blocks  []CARBlock
+}
+
+// ReadCAR decodes a CARv1, or the CARv1 payload of a CARv2, section by
+// section. Unlike the ucanto reader it keeps blocks whose bytes do not hash
+// to their CID, so callers can report them.
+func ReadCAR(data []byte) (*CARFile, error) {
+	header, offset, err := readCARHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := carVersion(header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case 1:
+		return readCARv1(data, header, offset)
+	case 2:
+		return readCARv2(data)
+	default:
+		return nil, fmt.Errorf("unsupported CAR version: %d", version)
+	}
+}
+
+// CheckCARv2 rejects data carrying the CARv2 pragma whose header does not
+// locate a CARv1 payload within it. Anything else passes, CAR or not.
+func CheckCARv2(data []byte) error {
+	if !bytes.HasPrefix(data, carV2Pragma) {
+		return nil
+	}
+	_, _, err := carV2Payload(data)
+	return err
+}
+
+// carV2Payload returns the CARv1 payload a CARv2 locates in its header, and
+// its offset. The payload must lie past the header and within the archive,
+// and must not be a CARv2 in turn, so a crafted archive cannot point back
+// at itself.
+func carV2Payload(data []byte) ([]byte, uint64, error) {
+	if len(data) < carV2PragmaSize+40 {
+		return nil, 0, fmt.Errorf("truncated CARv2 header")
+	}
+	v2 := data[carV2PragmaSize:]
+	dataOffset := binary.LittleEndian.Uint64(v2[16:24])
+	dataSize := binary.LittleEndian.Uint64(v2[24:32])
+	if dataOffset < carV2PragmaSize+40 || dataOffset > uint64(len(data)) || dataSize > uint64(len(data))-dataOffset {
+		return nil, 0, fmt.Errorf("CARv2 payload out of range")
+	}
+	payload := data[dataOffset : dataOffset+dataSize]
+
+	header, _, err := readCARHeader(payload)
+	if err != nil {
+		return nil, 0, fmt.Errorf("CARv2 payload: %w", err)
+	}
+	version, err := carVersion(header)
+	if err != nil {
+		return nil, 0, fmt.Errorf("CARv2 payload: %w", err)
+	}
+	if version != 1 {
+		return nil, 0, fmt.Errorf("CARv2 payload: unsupported CAR version: %d", version)
+	}
+	return payload, dataOffset, nil
+}
+
+// readCARv2 decodes the CARv1 payload of a CARv2
+func readCARv2(data []byte) (*CARFile, error) {
+	payload, dataOffset, err := carV2Payload(data)
+	if err != nil {
+		return nil, err
+	}
+	header, offset, err := readCARHeader(payload)
+	if err != nil {
+		return nil, fmt.Errorf("CARv2 payload: %w", err)
+	}
+
+	inner, err := readCARv1(payload, header, offset)
+	if err != nil {
+		return nil, fmt.Errorf("CARv2 payload: %w", err)
+	}
+	for i := range inner.Blocks {
+		inner.Blocks[i].Offset += dataOffset
+	}
+	inner.Version = 2
+	return inner, nil
+}
+
+// readCARv1 decodes the roots and sections of a CARv1 whose header has
+// already been read, the first section starting at offset
+func readCARv1(data []byte, header ipld.Node, offset uint64) (*CARFile, error) {
+	car := &CARFile{Version: 1}
+	roots, err := header.LookupByString("roots")
+	if err != nil || roots.Kind() != ipld.Kind_List {
+		return nil, fmt.Errorf("CAR header has no roots list")
+	}
+	iter := roots.ListIterator()
+	for !iter.Done() {
+		_, root, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		link, err := root.AsLink()
+		if err != nil {
+			return nil, fmt.Errorf("CAR root is not a link: %w", err)
+		}
+		cl, ok := link.(cidlink.Link)
+		if !ok {
+			return nil, fmt.Errorf("CAR root is not a CID")
+		}
+		car.Roots = append(car.Roots, cl.Cid)
+	}
+
+	for offset < uint64(len(data)) {
+		section, read := binary.Uvarint(data[offset:])
+		if read <= 0 {
+			return nil, fmt.Errorf("invalid section length at offset %d", offset)
+		}
+		start := offset + uint64(read)
+		if section > uint64(len(data))-start {
+			return nil, fmt.Errorf("truncated section at offset %d", offset)
+		}
+		body := data[start : start+section]
+
+		n, c, err := cid.CidFromBytes(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CID at offset %d: %w", offset, err)
+		}
+		car.Blocks = append(car.Blocks, CARBlock{
+			CID:    c,
+			Data:   body[n:],
+			Offset: offset,
+		})
+		offset = start + section
+	}
+
+	return car, nil
+}
+
+// readCARHeader decodes the length-prefixed DAG-CBOR header every CAR
+// starts with and returns it with the offset of the first section
+func readCARHeader(data []byte) (ipld.Node, uint64, error) {
+	length, read := binary.Uvarint(data)
+	if read <= 0 || length == 0 {
+		return nil, 0, fmt.Errorf("not a CAR: invalid header length")
+	}
+	if length > uint64(len(data)-read) {
+		return nil, 0, fmt.Errorf("not a CAR: truncated header")
+	}
+
+	nb := basicnode.Prototype.Any.NewBuilder()
+	if err := dagcbor.Decode(nb, bytes.NewReader(data[read:read+int(length)])); err != nil {
+		return nil, 0, fmt.Errorf("not a CAR: %w", err)
+	}
+	header := nb.Build()
+	if header.Kind() != ipld.Kind_Map {
+		return nil, 0, fmt.Errorf("not a CAR: header is not a map")
+	}
+	return header, uint64(read) + length, nil
+}
+
+func carVersion(header ipld.Node) (uint64, error) {
+	node, err := header.LookupByString("version")
+	if err != nil {
+		return 0, fmt.Errorf("CAR header has no version")
+	}
+	version, err := node.AsInt()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid CAR version")
+	}
+	return uint64(version), nil
+}
+
+// IntegrityError reports a block whose bytes do not hash to its CID
+type IntegrityError struct {
+	CID      cid.Cid
+	Computed cid.Cid // CID the bytes actually hash to
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("block %s hashes to %s", e.CID, e.Computed)
+}
+
+// VerifyBlock checks that data hashes to c under c's own codec and hash
+// function. A mismatch is reported as an *IntegrityError.
+func VerifyBlock(c cid.Cid, data []byte) error {
+	computed, err := c.Prefix().Sum(data)
+	if err != nil {
+		return fmt.Errorf("cannot hash block %s: %w", c, err)
+	}
+	if !computed.Equals(c) {
+		return &IntegrityError{CID: c, Computed: computed}
+	}
+	return nil
+}
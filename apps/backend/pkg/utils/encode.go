@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/ipld/go-ipld-prime"
@@ -24,13 +26,28 @@ type UCANClaims struct {
 	Proofs    []string                 `json:"prf"`
 	Att       []map[string]interface{} `json:"att"` // Capabilities
 	Cid       string                   `json:"cid,omitempty"`
+	Version   string                   `json:"ucv,omitempty"` // payload ucv (0.10) or v (CBOR 0.9)
+	AttShape  string                   `json:"-"`             // "list" (0.8, 0.9) or "map" (0.10)
+
+	// UCAN 1.0 fields
+	Subject   string                 `json:"sub,omitempty"`
+	Powerline bool                   `json:"-"` // sub is explicitly null
+	Command   string                 `json:"cmd,omitempty"`
+	Policy    []interface{}          `json:"pol,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+	IssuedAt  int64                  `json:"iat,omitempty"`
+	Cause     string                 `json:"cause,omitempty"`
 }
 
 // ParsedJWT holds the raw data we extracted
 type ParsedJWT struct {
-	Header    map[string]interface{}
-	Claims    UCANClaims
-	Signature []byte
+	Header       map[string]interface{}
+	Claims       UCANClaims
+	Signature    []byte
+	SigningInput []byte // bytes the issuer signed over
+	Envelope     string // UCAN 1.0 payload tag, e.g. "ucan/inv@1.0.0-rc.1"
+	VarsigHeader []byte // UCAN 1.0 "h" field
 }
 
 // ParseUnverifiedJWT decodes a standard JWT string (ey...)
@@ -55,8 +72,8 @@ func ParseUnverifiedJWT(tokenString string) (*ParsedJWT, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode payload: %w", err)
 	}
-	var claims UCANClaims
-	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+	claims, err := decodeJWTClaims(payloadBytes)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal claims: %w", err)
 	}
 
@@ -67,12 +84,85 @@ func ParseUnverifiedJWT(tokenString string) (*ParsedJWT, error) {
 	}
 
 	return &ParsedJWT{
-		Header:    header,
-		Claims:    claims,
-		Signature: sigBytes,
+		Header:       header,
+		Claims:       claims,
+		Signature:    sigBytes,
+		SigningInput: []byte(parts[0] + "." + parts[1]),
 	}, nil
 }
 
+// jwtPayload captures the claims whose JSON shape differs between UCAN 0.x
+// versions before they are normalized into UCANClaims
+type jwtPayload struct {
+	UCANClaims
+	Att   json.RawMessage `json:"att"`
+	Facts json.RawMessage `json:"fct"`
+}
+
+// decodeJWTClaims decodes a JWT payload. 0.8 and 0.9 list capabilities as
+// {with, can, nb} objects; 0.10 nests them as {resource: {ability: [caveats]}}
+// and turns fct into a map. Both come out as the 0.9 list.
+func decodeJWTClaims(payload []byte) (UCANClaims, error) {
+	var raw jwtPayload
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return UCANClaims{}, err
+	}
+	claims := raw.UCANClaims
+
+	switch att := bytes.TrimSpace(raw.Att); {
+	case len(att) == 0 || bytes.Equal(att, []byte("null")):
+	case att[0] == '[':
+		claims.AttShape = "list"
+		if err := json.Unmarshal(att, &claims.Att); err != nil {
+			return claims, fmt.Errorf("att: %w", err)
+		}
+	case att[0] == '{':
+		claims.AttShape = "map"
+		var byResource map[string]map[string][]map[string]interface{}
+		if err := json.Unmarshal(att, &byResource); err != nil {
+			return claims, fmt.Errorf("att: %w", err)
+		}
+		claims.Att = flattenAttMap(byResource)
+	default:
+		return claims, fmt.Errorf("att: expected a list or map")
+	}
+
+	switch fct := bytes.TrimSpace(raw.Facts); {
+	case len(fct) == 0 || bytes.Equal(fct, []byte("null")):
+	case fct[0] == '{':
+		var facts map[string]interface{}
+		if err := json.Unmarshal(fct, &facts); err != nil {
+			return claims, fmt.Errorf("fct: %w", err)
+		}
+		claims.Facts = []interface{}{facts}
+	default:
+		if err := json.Unmarshal(fct, &claims.Facts); err != nil {
+			return claims, fmt.Errorf("fct: %w", err)
+		}
+	}
+
+	return claims, nil
+}
+
+// flattenAttMap turns a 0.10 capability map into {with, can, nb} entries,
+// one per caveat, in a stable order. An empty caveat object means none.
+func flattenAttMap(byResource map[string]map[string][]map[string]interface{}) []map[string]interface{} {
+	var att []map[string]interface{}
+	for _, with := range slices.Sorted(maps.Keys(byResource)) {
+		abilities := byResource[with]
+		for _, can := range slices.Sorted(maps.Keys(abilities)) {
+			for _, caveat := range abilities[can] {
+				entry := map[string]interface{}{"with": with, "can": can}
+				if len(caveat) > 0 {
+					entry["nb"] = caveat
+				}
+				att = append(att, entry)
+			}
+		}
+	}
+	return att
+}
+
 // ParseUnverifiedCBOR decodes a Raw UCAN Block (glhA...)
 func ParseUnverifiedCBOR(data []byte) (*ParsedJWT, error) {
 	// 1. Decode DAG-CBOR into a Generic Node
@@ -88,7 +178,10 @@ func ParseUnverifiedCBOR(data []byte) (*ParsedJWT, error) {
 
 	claims := UCANClaims{}
 	var sigBytes []byte
+	var signingInput []byte
 	var header map[string]interface{}
+	var envelope string
+	var varsig []byte
 
 	iter := node.ListIterator()
 	for !iter.Done() {
@@ -106,7 +199,18 @@ func ParseUnverifiedCBOR(data []byte) (*ParsedJWT, error) {
 				if strings.HasPrefix(kStr, "ucan/") {
 					log.Printf("[DEBUG] Found UCAN Version Key: %s", kStr)
 					claims = extractClaims(v)
+					envelope = kStr
 					foundNested = true
+				} else if kStr == "h" {
+					varsig, _ = v.AsBytes()
+				}
+			}
+
+			// The envelope signature covers the DAG-CBOR encoded payload map
+			if foundNested {
+				var buf bytes.Buffer
+				if err := dagcbor.Encode(item, &buf); err == nil {
+					signingInput = buf.Bytes()
 				}
 			}
 
@@ -115,13 +219,13 @@ func ParseUnverifiedCBOR(data []byte) (*ParsedJWT, error) {
 				if tempClaims.Issuer != "" || tempClaims.Audience != "" {
 					claims = tempClaims
 				} else {
-					header = extractMap(item)
+					header = NodeToMap(item)
 				}
 			}
 
 		} else if item.Kind() == ipld.Kind_Bytes {
 			b, _ := item.AsBytes()
-			if len(b) == 64 {
+			if len(b) > 0 {
 				sigBytes = b
 			}
 		}
@@ -132,9 +236,12 @@ func ParseUnverifiedCBOR(data []byte) (*ParsedJWT, error) {
 	}
 
 	return &ParsedJWT{
-		Header:    header,
-		Claims:    claims,
-		Signature: sigBytes,
+		Header:       header,
+		Claims:       claims,
+		Signature:    sigBytes,
+		SigningInput: signingInput,
+		Envelope:     envelope,
+		VarsigHeader: varsig,
 	}, nil
 }
 
@@ -145,11 +252,6 @@ func extractClaims(node ipld.Node) UCANClaims {
 		return claims
 	}
 	
-	// Variables to hold iso-ucan specific fields
-	var cmd string
-	var sub string
-	var pol interface{}
-
 	pIter := node.MapIterator()
 	for !pIter.Done() {
 		k, v, _ := pIter.Next()
@@ -158,21 +260,49 @@ func extractClaims(node ipld.Node) UCANClaims {
 		log.Printf("[DEBUG] Claims Key Found: %s", keyStr)
 
 		switch keyStr {
-		case "iss": claims.Issuer, _ = v.AsString()
-		case "aud": claims.Audience, _ = v.AsString()
-		case "exp": exp, _ := v.AsInt(); claims.Expiry = exp
-		case "nbf": nbf, _ := v.AsInt(); claims.NotBefore = nbf
-		case "nnc": claims.Nonce, _ = v.AsString()
-		
-		// --- CAPTURE ISO-UCAN FIELDS ---
-		case "cmd":
-			cmd, _ = v.AsString()
+		case "iss":
+			claims.Issuer, _ = v.AsString()
+		case "aud":
+			claims.Audience, _ = v.AsString()
+		case "exp":
+			exp, _ := v.AsInt()
+			claims.Expiry = exp
+		case "nbf":
+			nbf, _ := v.AsInt()
+			claims.NotBefore = nbf
+		case "nnc":
+			claims.Nonce, _ = v.AsString()
+		case "v":
+			claims.Version, _ = v.AsString()
+
+		// --- UCAN 1.0 FIELDS ---
 		case "sub":
-			sub, _ = v.AsString()
+			if v.IsNull() {
+				claims.Powerline = true
+			} else {
+				claims.Subject, _ = v.AsString()
+			}
+		case "cmd":
+			claims.Command, _ = v.AsString()
 		case "pol":
-			pol = nodeToValue(v)
-			// Keep adding to facts as well, as it is technically a fact/assertion
-			claims.Facts = append(claims.Facts, map[string]interface{}{"pol": pol})
+			if pol, ok := NodeToValue(v).([]interface{}); ok {
+				claims.Policy = pol
+			}
+		case "args":
+			claims.Args = NodeToMap(v)
+		case "meta":
+			claims.Meta = NodeToMap(v)
+		case "iat":
+			iat, _ := v.AsInt()
+			claims.IssuedAt = iat
+		case "nonce":
+			if nonce, err := v.AsBytes(); err == nil {
+				claims.Nonce = base64.StdEncoding.EncodeToString(nonce)
+			}
+		case "cause":
+			if link, err := v.AsLink(); err == nil {
+				claims.Cause = link.String()
+			}
 
 		case "prf":
 			if v.Kind() == ipld.Kind_List {
@@ -189,6 +319,7 @@ func extractClaims(node ipld.Node) UCANClaims {
 
 		case "att", "capabilities", "caps": 
 			if v.Kind() == ipld.Kind_List {
+				claims.AttShape = "list"
 				lIter := v.ListIterator()
 				for !lIter.Done() {
 					_, capNode, _ := lIter.Next()
@@ -198,7 +329,7 @@ func extractClaims(node ipld.Node) UCANClaims {
 						for !cIter.Done() {
 							ck, cv, _ := cIter.Next()
 							ckStr, _ := ck.AsString()
-							capMap[ckStr] = nodeToValue(cv)
+							capMap[ckStr] = NodeToValue(cv)
 						}
 						claims.Att = append(claims.Att, capMap)
 					}
@@ -207,74 +338,12 @@ func extractClaims(node ipld.Node) UCANClaims {
 		}
 	}
 
-	// --- SYNTHESIZE CAPABILITY ---
-	// If we found 'cmd' (Action) but no standard 'att' array, map it!
-	if len(claims.Att) == 0 && cmd != "" {
-		log.Printf("[DEBUG] Mapping iso-ucan 'cmd' to standard Capability...")
-		capMap := map[string]interface{}{
-			"can": cmd,  // cmd -> can
-			"with": sub, // sub -> with
-		}
-		// If there is a policy, wrap it as a caveat
-		if pol != nil {
-			capMap["nb"] = map[string]interface{}{"pol": pol}
-		}
-		claims.Att = append(claims.Att, capMap)
-	}
-
 	return claims
 }
 
-func extractMap(node ipld.Node) map[string]interface{} {
-	m := make(map[string]interface{})
-	iter := node.MapIterator()
-	for !iter.Done() {
-		k, v, _ := iter.Next()
-		kStr, _ := k.AsString()
-		m[kStr] = nodeToValue(v)
-	}
-	return m
-}
-
 func parseBase64(input string) ([]byte, error) {
 	if l := len(input) % 4; l > 0 {
 		input += strings.Repeat("=", 4-l)
 	}
 	return base64.URLEncoding.DecodeString(input)
 }
-
-func nodeToValue(node ipld.Node) interface{} {
-	switch node.Kind() {
-	case ipld.Kind_Bool:
-		v, _ := node.AsBool()
-		return v
-	case ipld.Kind_Int:
-		v, _ := node.AsInt()
-		return v
-	case ipld.Kind_Float:
-		v, _ := node.AsFloat()
-		return v
-	case ipld.Kind_String:
-		v, _ := node.AsString()
-		return v
-	case ipld.Kind_Bytes:
-		v, _ := node.AsBytes()
-		return v
-	case ipld.Kind_Link:
-		v, _ := node.AsLink()
-		return v.String()
-	case ipld.Kind_Map:
-		return extractMap(node)
-	case ipld.Kind_List:
-		var l []interface{}
-		iter := node.ListIterator()
-		for !iter.Done() {
-			_, v, _ := iter.Next()
-			l = append(l, nodeToValue(v))
-		}
-		return l
-	default:
-		return nil
-	}
-}
-
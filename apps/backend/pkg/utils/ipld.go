@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ipld/go-ipld-prime"
+)
+
+// LinkValue is a decoded IPLD link. It marshals to the DAG-JSON link form
+// {"/": "<cid>"} so links stay distinguishable from plain strings.
+type LinkValue struct {
+	CID string `json:"/"`
+}
+
+// BytesValue is a decoded IPLD byte string. It marshals to the DAG-JSON
+// bytes form {"/": {"bytes": "<base64>"}} so bytes stay distinguishable
+// from plain strings.
+type BytesValue []byte
+
+// MarshalJSON renders the bytes in DAG-JSON form
+func (b BytesValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]map[string]string{
+		"/": {"bytes": base64.RawStdEncoding.EncodeToString(b)},
+	})
+}
+
+// NodeToValue decodes an IPLD node into a JSON-friendly Go value tree.
+// Maps become map[string]interface{}, lists []interface{}, links LinkValue
+// and byte strings BytesValue; scalars map to their Go equivalents.
+func NodeToValue(node ipld.Node) interface{} {
+	if node == nil {
+		return nil
+	}
+
+	switch node.Kind() {
+	case ipld.Kind_Bool:
+		v, _ := node.AsBool()
+		return v
+	case ipld.Kind_Int:
+		v, _ := node.AsInt()
+		return v
+	case ipld.Kind_Float:
+		v, _ := node.AsFloat()
+		return v
+	case ipld.Kind_String:
+		v, _ := node.AsString()
+		return v
+	case ipld.Kind_Bytes:
+		v, _ := node.AsBytes()
+		return BytesValue(v)
+	case ipld.Kind_Link:
+		v, _ := node.AsLink()
+		return LinkValue{CID: v.String()}
+	case ipld.Kind_Map:
+		return NodeToMap(node)
+	case ipld.Kind_List:
+		l := make([]interface{}, 0, node.Length())
+		iter := node.ListIterator()
+		for !iter.Done() {
+			_, v, err := iter.Next()
+			if err != nil {
+				break
+			}
+			l = append(l, NodeToValue(v))
+		}
+		return l
+	default:
+		return nil
+	}
+}
+
+// NodeToMap decodes an IPLD map node. Non-map nodes yield an empty map.
+func NodeToMap(node ipld.Node) map[string]interface{} {
+	m := make(map[string]interface{})
+	if node == nil || node.Kind() != ipld.Kind_Map {
+		return m
+	}
+
+	iter := node.MapIterator()
+	for !iter.Done() {
+		k, v, err := iter.Next()
+		if err != nil {
+			break
+		}
+		key, err := k.AsString()
+		if err != nil {
+			key = fmt.Sprint(NodeToValue(k))
+		}
+		m[key] = NodeToValue(v)
+	}
+	return m
+}
+
+// AnyToValue decodes values that may or may not be IPLD nodes, as returned
+// by go-ucanto accessors typed as `any`.
+func AnyToValue(v any) interface{} {
+	switch val := v.(type) {
+	case ipld.Node:
+		return NodeToValue(val)
+	case ipld.Link:
+		return LinkValue{CID: val.String()}
+	case []byte:
+		return BytesValue(val)
+	case map[string]any:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = AnyToValue(item)
+		}
+		return m
+	case []any:
+		l := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			l = append(l, AnyToValue(item))
+		}
+		return l
+	default:
+		return val
+	}
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+)
+
+// Token formats accepted by DecodeToken
+const (
+	FormatAuto      = "auto"
+	FormatBase64    = "base64"
+	FormatBase64URL = "base64url"
+	FormatHex       = "hex"
+	FormatMultibase = "multibase"
+	FormatJWT       = "jwt"
+	FormatRaw       = "raw"
+)
+
+// autoMultibases are the multibase prefixes tried when detecting a format.
+// Other prefixes are only accepted with an explicit "multibase" format, as a
+// single leading character is too weak a signal on its own.
+var autoMultibases = map[byte]bool{
+	'm': true, // base64
+	'u': true, // base64url
+	'b': true, // base32
+	'z': true, // base58btc
+	'f': true, // base16
+}
+
+// TokenEncoding describes how a pasted token was encoded
+type TokenEncoding struct {
+	Format      string `json:"format"`
+	Multibase   string `json:"multibase,omitempty"`   // e.g. "base64" for an "m" prefix
+	IdentityCID bool   `json:"identityCid,omitempty"` // bytes were a CID whose identity multihash holds the token
+}
+
+// String renders the encoding as reported in the X-Token-Encoding header,
+// e.g. "base64", "multibase-base64+identity-cid"
+func (e TokenEncoding) String() string {
+	s := e.Format
+	if e.Multibase != "" {
+		s += "-" + e.Multibase
+	}
+	if e.IdentityCID {
+		s += "+identity-cid"
+	}
+	return s
+}
+
+// DecodeToken decodes a token pasted as text. An empty format or "auto"
+// detects the encoding; the result reports which one was used. A CID with
+// an identity multihash, as printed by `w3 delegation create`, is unwrapped
+// to the CAR it carries whatever encoding it came in.
+func DecodeToken(token, format string) ([]byte, TokenEncoding, error) {
+	token = strings.TrimSpace(token)
+
+	var (
+		data []byte
+		enc  TokenEncoding
+		err  error
+	)
+	switch strings.ToLower(format) {
+	case "", FormatAuto, FormatRaw:
+		// "raw" predates detection and always meant "decode if encoded"
+		data, enc = detectToken(token)
+	case FormatBase64:
+		enc.Format = FormatBase64
+		data, err = decodeBase64(token, base64.StdEncoding)
+	case FormatBase64URL:
+		enc.Format = FormatBase64URL
+		data, err = decodeBase64(token, base64.URLEncoding)
+	case FormatHex:
+		enc.Format = FormatHex
+		data, err = hex.DecodeString(strings.TrimPrefix(token, "0x"))
+	case FormatMultibase:
+		enc.Format = FormatMultibase
+		var base multibase.Encoding
+		base, data, err = multibase.Decode(token)
+		enc.Multibase = multibase.EncodingToStr[base]
+	case FormatJWT:
+		enc.Format = FormatJWT
+		data = []byte(token)
+	default:
+		return nil, enc, fmt.Errorf("unsupported format: %s", format)
+	}
+	if err != nil {
+		return nil, enc, fmt.Errorf("invalid %s token: %w", enc.Format, err)
+	}
+
+	if inner, ok := unwrapIdentityCID(data); ok {
+		data = inner
+		enc.IdentityCID = true
+	}
+
+	if err := CheckCARv2(data); err != nil {
+		return nil, enc, fmt.Errorf("invalid CAR: %w", err)
+	}
+
+	return data, enc, nil
+}
+
+// detectToken tries each encoding the token could be in and keeps the first
+// whose bytes look like a UCAN. When none do, the first successful decoding
+// wins and text that decodes as nothing is passed through raw.
+func detectToken(token string) ([]byte, TokenEncoding) {
+	if IsJWT(token) {
+		return []byte(token), TokenEncoding{Format: FormatJWT}
+	}
+
+	type candidate struct {
+		data []byte
+		enc  TokenEncoding
+	}
+	var candidates []candidate
+
+	if data, err := decodeBase64(token, base64.StdEncoding); err == nil {
+		candidates = append(candidates, candidate{data, TokenEncoding{Format: FormatBase64}})
+	}
+	if len(token) > 1 && autoMultibases[token[0]] {
+		if base, data, err := multibase.Decode(token); err == nil {
+			candidates = append(candidates, candidate{data, TokenEncoding{
+				Format:    FormatMultibase,
+				Multibase: multibase.EncodingToStr[base],
+			}})
+		}
+	}
+	if data, err := hex.DecodeString(strings.TrimPrefix(token, "0x")); err == nil {
+		candidates = append(candidates, candidate{data, TokenEncoding{Format: FormatHex}})
+	}
+	if data, err := decodeBase64(token, base64.URLEncoding); err == nil {
+		candidates = append(candidates, candidate{data, TokenEncoding{Format: FormatBase64URL}})
+	}
+
+	for _, c := range candidates {
+		if looksLikeToken(c.data) {
+			return c.data, c.enc
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0].data, candidates[0].enc
+	}
+	return []byte(token), TokenEncoding{Format: FormatRaw}
+}
+
+// decodeBase64 decodes padded or unpadded base64 in the given alphabet
+func decodeBase64(token string, enc *base64.Encoding) ([]byte, error) {
+	if strings.HasSuffix(token, "=") {
+		return enc.DecodeString(token)
+	}
+	return enc.WithPadding(base64.NoPadding).DecodeString(token)
+}
+
+// IsJWT reports whether token has the three base64url sections of a JWT
+func IsJWT(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "eyJ") {
+		return false
+	}
+	for _, part := range parts[:2] {
+		if _, err := base64.RawURLEncoding.DecodeString(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// looksLikeToken reports whether decoded bytes have the shape of something
+// the parser understands: a CAR, a DAG-CBOR list or map, a JWT or an
+// identity CID
+func looksLikeToken(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if _, ok := unwrapIdentityCID(data); ok {
+		return true
+	}
+	if IsJWT(string(data)) {
+		return true
+	}
+
+	// CARv1: a varint length followed by a DAG-CBOR header map
+	if n, read := binary.Uvarint(data); read > 0 && n > 0 && uint64(len(data)-read) >= n {
+		if kind, ok := cborKind(data[read : read+int(n)]); ok && kind == ipld.Kind_Map {
+			return true
+		}
+	}
+
+	// DAG-CBOR array (UCAN envelope) or map (UCAN container)
+	kind, ok := cborKind(data)
+	return ok && (kind == ipld.Kind_List || kind == ipld.Kind_Map)
+}
+
+// cborKind decodes data as a single DAG-CBOR value and reports the kind of
+// its root node. Trailing bytes mean data is not DAG-CBOR.
+func cborKind(data []byte) (ipld.Kind, bool) {
+	nb := basicnode.Prototype.Any.NewBuilder()
+	r := bytes.NewReader(data)
+	if err := dagcbor.Decode(nb, r); err != nil || r.Len() != 0 {
+		return ipld.Kind_Invalid, false
+	}
+	return nb.Build().Kind(), true
+}
+
+// unwrapIdentityCID returns the bytes held by a binary CID whose multihash
+// is the identity hash
+func unwrapIdentityCID(data []byte) ([]byte, bool) {
+	// CIDv1 starts with version 1; anything else is not worth casting
+	if len(data) < 4 || data[0] != 0x01 {
+		return nil, false
+	}
+
+	n, c, err := cid.CidFromBytes(data)
+	if err != nil || n != len(data) || c.Prefix().MhType != multihash.IDENTITY {
+		return nil, false
+	}
+
+	decoded, err := multihash.Decode(c.Hash())
+	if err != nil || len(decoded.Digest) == 0 {
+		return nil, false
+	}
+	return bytes.Clone(decoded.Digest), true
+}
+
+// TokenCID computes the CIDv1 a raw token is addressed by, with a sha2-256
+// multihash: the raw codec over the token text for JWTs, which is how UCAN
+// 0.x proofs cite them, and DAG-CBOR for CBOR blocks
+func TokenCID(data []byte) (cid.Cid, error) {
+	prefix := cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagCBOR,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}
+
+	if token := strings.TrimSpace(string(data)); IsJWT(token) {
+		prefix.Codec = cid.Raw
+		data = []byte(token)
+	}
+
+	return prefix.Sum(data)
+}
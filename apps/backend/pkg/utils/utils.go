@@ -12,31 +12,8 @@ import (
 
 // NormalizeToken converts token from various formats to bytes for CAR parsing
 func NormalizeToken(token, format string) ([]byte, error) {
-	token = strings.TrimSpace(token)
-	
-	switch strings.ToLower(format) {
-	case "base64":
-		// Decode base64 to get CAR bytes
-		decoded, err := base64.StdEncoding.DecodeString(token)
-		if err != nil {
-			return nil, fmt.Errorf("invalid base64 token: %w", err)
-		}
-		return decoded, nil
-	case "raw", "":
-		// For CAR format, we need to decode base64 by default
-		// since our token generator outputs base64-encoded CAR data
-		if isBase64(token) {
-			decoded, err := base64.StdEncoding.DecodeString(token)
-			if err != nil {
-				// If base64 decode fails, return as raw bytes
-				return []byte(token), nil
-			}
-			return decoded, nil
-		}
-		return []byte(token), nil
-	default:
-		return nil, fmt.Errorf("unsupported format: %s", format)
-	}
+	data, _, err := DecodeToken(token, format)
+	return data, err
 }
 
 // ReadUploadedFile reads the contents of an uploaded file
@@ -52,10 +29,13 @@ func ReadUploadedFile(file multipart.File, header *multipart.FileHeader) ([]byte
 	if isBase64(content) {
 		decoded, err := base64.StdEncoding.DecodeString(content)
 		if err == nil {
-			return decoded, nil
+			data = decoded
 		}
 	}
 
+	if err := CheckCARv2(data); err != nil {
+		return nil, fmt.Errorf("invalid CAR: %w", err)
+	}
 	return data, nil
 }
 
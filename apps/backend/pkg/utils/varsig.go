@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/multiformats/go-multicodec"
+)
+
+// varsigPrefix is the multicodec that opens every varsig header
+const varsigPrefix = 0x34
+
+// VarsigHeader is a decoded varsig header, as carried in the "h" field of
+// a UCAN 1.0 envelope
+type VarsigHeader struct {
+	Algorithm       string   // JWT-style name, e.g. EdDSA or ES256
+	KeyType         string   // multicodec name of the signing key, e.g. ed25519-pub
+	Hash            string   // multicodec name of the payload hash, e.g. sha2-512
+	PayloadEncoding string   // multicodec name of the signed payload, e.g. dag-cbor
+	Codes           []uint64 // every code after the prefix, in order
+}
+
+// keyAlgorithms maps key type codes to the signature algorithm they imply
+var keyAlgorithms = map[multicodec.Code]string{
+	multicodec.Ed25519Pub:   "EdDSA",
+	multicodec.P256Pub:      "ES256",
+	multicodec.P384Pub:      "ES384",
+	multicodec.P521Pub:      "ES512",
+	multicodec.Secp256k1Pub: "ES256K",
+	multicodec.RsaPub:       "RS256",
+}
+
+// impliedHashes gives the hash of algorithms whose header omits it
+var impliedHashes = map[multicodec.Code]multicodec.Code{
+	multicodec.Ed25519Pub: multicodec.Sha2_512,
+}
+
+// DecodeVarsigHeader decodes a varsig header: the 0x34 prefix, the key type,
+// any algorithm parameters such as the hash, and finally the payload encoding
+func DecodeVarsigHeader(h []byte) (*VarsigHeader, error) {
+	if len(h) == 0 || h[0] != varsigPrefix {
+		return nil, fmt.Errorf("not a varsig header: missing 0x%x prefix", varsigPrefix)
+	}
+
+	var codes []uint64
+	for rest := h[1:]; len(rest) > 0; {
+		code, n := binary.Uvarint(rest)
+		if n <= 0 {
+			return nil, fmt.Errorf("malformed varint at offset %d", len(h)-len(rest))
+		}
+		codes = append(codes, code)
+		rest = rest[n:]
+	}
+
+	if len(codes) < 2 {
+		return nil, fmt.Errorf("varsig header needs a key type and a payload encoding, got %d codes", len(codes))
+	}
+
+	keyType := multicodec.Code(codes[0])
+	header := &VarsigHeader{
+		Algorithm:       keyAlgorithms[keyType],
+		KeyType:         keyType.String(),
+		PayloadEncoding: multicodec.Code(codes[len(codes)-1]).String(),
+		Codes:           codes,
+	}
+	if header.Algorithm == "" {
+		header.Algorithm = "unknown"
+	}
+
+	for _, code := range codes[1 : len(codes)-1] {
+		if multicodec.Code(code).Tag() == "multihash" {
+			header.Hash = multicodec.Code(code).String()
+		}
+	}
+	if implied, ok := impliedHashes[keyType]; ok && header.Hash == "" {
+		header.Hash = implied.String()
+	}
+
+	return header, nil
+}
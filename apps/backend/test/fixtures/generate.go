@@ -1,11 +1,38 @@
 package fixtures
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"time"
 	"io"
 
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime/codec/dagcbor"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	"github.com/ipld/go-ipld-prime/fluent/qp"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	"github.com/ipld/go-ipld-prime/node/basicnode"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multicodec"
+	"github.com/multiformats/go-multihash"
+	"github.com/storacha/go-ucanto/core/car"
+	"github.com/storacha/go-ucanto/core/dag/blockstore"
 	"github.com/storacha/go-ucanto/core/delegation"
+	"github.com/storacha/go-ucanto/core/invocation"
+	"github.com/storacha/go-ucanto/core/ipld"
+	"github.com/storacha/go-ucanto/core/message"
+	"github.com/storacha/go-ucanto/core/receipt"
+	"github.com/storacha/go-ucanto/core/receipt/fx"
+	"github.com/storacha/go-ucanto/core/receipt/ran"
+	"github.com/storacha/go-ucanto/core/result"
+	"github.com/storacha/go-ucanto/core/result/ok"
+	"github.com/storacha/go-ucanto/principal"
 	"github.com/storacha/go-ucanto/principal/ed25519/signer"
+	rsasigner "github.com/storacha/go-ucanto/principal/rsa/signer"
+	principalsigner "github.com/storacha/go-ucanto/principal/signer"
 	"github.com/storacha/go-ucanto/ucan"
 )
 
@@ -137,4 +164,1382 @@ func GenerateComplexChain() ([]byte, error) {
 
 	archive := bobToCharlie.Archive()
 	return io.ReadAll(archive)
-}
\ No newline at end of file
+}
+
+// GenerateForgedUCAN creates a delegation that claims Alice as issuer but is
+// signed with Mallory's key
+func GenerateForgedUCAN() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	mallory, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	// Mallory's key wearing Alice's DID
+	forger, err := principalsigner.Wrap(mallory, alice.DID())
+	if err != nil {
+		return nil, err
+	}
+
+	del, err := delegation.Delegate(
+		forger,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability(
+				"store/add",
+				"storage:alice/*",
+				ucan.NoCaveats{},
+			),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := del.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateRSAUCAN creates a valid UCAN delegation from an RSA did:key issuer
+func GenerateRSAUCAN() ([]byte, error) {
+	alice, err := rsasigner.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	del, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := del.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateJWTUCAN creates a UCAN 0.9 JWT signed with EdDSA
+func GenerateJWTUCAN() (string, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]interface{}{
+		"alg": "EdDSA",
+		"typ": "JWT",
+		"ucv": "0.9.1",
+	}
+	payload := map[string]interface{}{
+		"iss": alice.DID().String(),
+		"aud": bob.DID().String(),
+		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"att": []map[string]interface{}{
+			{"with": "storage:alice/*", "can": "store/add"},
+		},
+		"prf": []string{},
+	}
+
+	return signJWT(alice, header, payload)
+}
+
+// GenerateRSAJWTUCAN creates a UCAN 0.9 JWT signed with RS256 by an RSA
+// did:key issuer
+func GenerateRSAJWTUCAN() (string, error) {
+	alice, err := rsasigner.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]interface{}{
+		"alg": "RS256",
+		"typ": "JWT",
+		"ucv": "0.9.1",
+	}
+	payload := map[string]interface{}{
+		"iss": alice.DID().String(),
+		"aud": bob.DID().String(),
+		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"att": []map[string]interface{}{
+			{"with": "storage:alice/*", "can": "store/add"},
+		},
+		"prf": []string{},
+	}
+
+	return signJWT(alice, header, payload)
+}
+
+// GenerateJWTChain creates a UCAN 0.9 JWT from Alice to Bob and a JWT from
+// Bob to Carol citing it in prf by its raw-codec CID
+func GenerateJWTChain() (leaf string, proof string, err error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return "", "", err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return "", "", err
+	}
+
+	carol, err := signer.Generate()
+	if err != nil {
+		return "", "", err
+	}
+
+	header := map[string]interface{}{
+		"alg": "EdDSA",
+		"typ": "JWT",
+		"ucv": "0.9.1",
+	}
+
+	proof, err = signJWT(alice, header, map[string]interface{}{
+		"iss": alice.DID().String(),
+		"aud": bob.DID().String(),
+		"exp": time.Now().Add(48 * time.Hour).Unix(),
+		"att": []map[string]interface{}{
+			{"with": "storage:alice/*", "can": "store/*"},
+		},
+		"prf": []string{},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	proofCID, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: multihash.SHA2_256, MhLength: -1}.Sum([]byte(proof))
+	if err != nil {
+		return "", "", err
+	}
+
+	leaf, err = signJWT(bob, header, map[string]interface{}{
+		"iss": bob.DID().String(),
+		"aud": carol.DID().String(),
+		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"att": []map[string]interface{}{
+			{"with": "storage:alice/photos/*", "can": "store/add"},
+		},
+		"prf": []string{proofCID.String()},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return leaf, proof, nil
+}
+
+// GenerateInlineJWTChain creates a UCAN 0.8 chain Alice -> Bob -> Carol ->
+// Dave where each JWT carries its proof inline in prf
+func GenerateInlineJWTChain() (string, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	carol, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	dave, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]interface{}{
+		"alg": "EdDSA",
+		"typ": "JWT",
+		"ucv": "0.8.1",
+	}
+
+	links := []struct {
+		issuer   ucan.Signer
+		audience ucan.Principal
+		can      string
+	}{
+		{alice, bob, "store/*"},
+		{bob, carol, "store/*"},
+		{carol, dave, "store/add"},
+	}
+
+	var token string
+	for i, link := range links {
+		prf := []string{}
+		if token != "" {
+			prf = append(prf, token)
+		}
+
+		token, err = signJWT(link.issuer, header, map[string]interface{}{
+			"iss": link.issuer.DID().String(),
+			"aud": link.audience.DID().String(),
+			"exp": time.Now().Add(time.Duration(72-24*i) * time.Hour).Unix(),
+			"att": []map[string]interface{}{
+				{"with": "storage:alice/*", "can": link.can},
+			},
+			"prf": prf,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return token, nil
+}
+
+// GenerateJWT010UCAN creates a UCAN 0.10 JWT: ucv moves into the payload and
+// att and fct become maps
+func GenerateJWT010UCAN() (string, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	return signJWT(alice, map[string]interface{}{
+		"alg": "EdDSA",
+		"typ": "JWT",
+	}, map[string]interface{}{
+		"ucv": "0.10.0",
+		"iss": alice.DID().String(),
+		"aud": bob.DID().String(),
+		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"nnc": "6b1c4c55",
+		"att": map[string]interface{}{
+			"storage:alice/*": map[string]interface{}{
+				"store/add": []map[string]interface{}{{}},
+			},
+		},
+		"fct": map[string]interface{}{"note": "0.10 facts are a map"},
+		"prf": []string{},
+	})
+}
+
+// GenerateMixedVersionChain creates a UCAN 0.10 JWT from Bob to Carol citing
+// a UCAN 0.9 JWT from Alice to Bob, returned as a whitespace-separated bundle
+func GenerateMixedVersionChain() (string, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	carol, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	proof, err := signJWT(alice, map[string]interface{}{
+		"alg": "EdDSA",
+		"typ": "JWT",
+		"ucv": "0.9.1",
+	}, map[string]interface{}{
+		"iss": alice.DID().String(),
+		"aud": bob.DID().String(),
+		"exp": time.Now().Add(48 * time.Hour).Unix(),
+		"att": []map[string]interface{}{
+			{"with": "storage:alice/*", "can": "store/*"},
+		},
+		"prf": []string{},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	proofCID, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: multihash.SHA2_256, MhLength: -1}.Sum([]byte(proof))
+	if err != nil {
+		return "", err
+	}
+
+	leaf, err := signJWT(bob, map[string]interface{}{
+		"alg": "EdDSA",
+		"typ": "JWT",
+	}, map[string]interface{}{
+		"ucv": "0.10.0",
+		"iss": bob.DID().String(),
+		"aud": carol.DID().String(),
+		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"att": map[string]interface{}{
+			"storage:alice/*": map[string]interface{}{
+				"store/add": []map[string]interface{}{{}},
+			},
+		},
+		"prf": []string{proofCID.String()},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return leaf + "\n" + proof, nil
+}
+
+// GenerateMislabelledJWT creates a JWT declaring UCAN 0.9 in its header but
+// using the 0.10 att map
+func GenerateMislabelledJWT() (string, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	return signJWT(alice, map[string]interface{}{
+		"alg": "EdDSA",
+		"typ": "JWT",
+		"ucv": "0.9.1",
+	}, map[string]interface{}{
+		"iss": alice.DID().String(),
+		"aud": bob.DID().String(),
+		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"att": map[string]interface{}{
+			"storage:alice/*": map[string]interface{}{
+				"store/add": []map[string]interface{}{{}},
+			},
+		},
+		"prf": []string{},
+	})
+}
+
+// signJWT encodes and signs a JWT with the given ucanto signer
+func signJWT(issuer ucan.Signer, header, payload map[string]interface{}) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(payloadJSON)
+	sig := issuer.Sign([]byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig.Raw()), nil
+}
+
+// nodeCaveats wraps a prebuilt IPLD node as capability caveats
+type nodeCaveats struct {
+	node datamodel.Node
+}
+
+func (c nodeCaveats) ToIPLD() (datamodel.Node, error) {
+	return c.node, nil
+}
+
+// GenerateBlobAddUCAN creates a space/blob/add delegation with nested
+// Storacha-style caveats containing bytes, ints and a link
+func GenerateBlobAddUCAN() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := cid.Parse("bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	if err != nil {
+		return nil, err
+	}
+
+	nb, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "blob", qp.Map(2, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "digest", qp.Bytes([]byte{0x12, 0x20, 0xde, 0xad, 0xbe, 0xef}))
+			qp.MapEntry(ma, "size", qp.Int(1024))
+		}))
+		qp.MapEntry(ma, "root", qp.Link(cidlink.Link{Cid: root}))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	del, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[nodeCaveats]{
+			ucan.NewCapability(
+				"space/blob/add",
+				alice.DID().String(),
+				nodeCaveats{node: nb},
+			),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := del.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateCaveatChain creates a space/blob/add chain: Alice delegates to
+// Bob with the numeric caveat key set to proofValue, and Bob passes the
+// ability on to Charlie with it set to childValue
+func GenerateCaveatChain(key string, proofValue, childValue int64) ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	charlie, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	caveats := func(value int64) (nodeCaveats, error) {
+		nb, err := qp.BuildMap(basicnode.Prototype.Any, 1, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, key, qp.Int(value))
+		})
+		return nodeCaveats{node: nb}, err
+	}
+
+	proofNb, err := caveats(proofValue)
+	if err != nil {
+		return nil, err
+	}
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[nodeCaveats]{
+			ucan.NewCapability("space/blob/add", alice.DID().String(), proofNb),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	childNb, err := caveats(childValue)
+	if err != nil {
+		return nil, err
+	}
+	bobToCharlie, err := delegation.Delegate(
+		bob,
+		charlie,
+		[]ucan.Capability[nodeCaveats]{
+			ucan.NewCapability("space/blob/add", alice.DID().String(), childNb),
+		},
+		delegation.WithExpiration(int(time.Now().Add(12*time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(aliceToBob)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := bobToCharlie.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateSelfIssuedWithProof creates a delegation from Alice on her own
+// resource that nonetheless carries a proof from Carol to Alice
+func GenerateSelfIssuedWithProof() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	carol, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	carolToAlice, err := delegation.Delegate(
+		carol,
+		alice,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", carol.DID().String(), ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", alice.DID().String(), ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(12*time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(carolToAlice)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := aliceToBob.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateMisalignedChain creates a chain where Charlie re-delegates a proof
+// that was issued to Bob
+func GenerateMisalignedChain() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	charlie, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	dave, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(7*24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Charlie was never granted anything, but cites Alice->Bob as proof
+	charlieToDave, err := delegation.Delegate(
+		charlie,
+		dave,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(aliceToBob)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := charlieToDave.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateEscalatedChain creates a chain where Bob re-delegates a broader
+// ability than Alice granted him
+func GenerateEscalatedChain() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	charlie, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(7*24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// store/* is broader than the store/add Bob holds
+	bobToCharlie, err := delegation.Delegate(
+		bob,
+		charlie,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/*", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(aliceToBob)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := bobToCharlie.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateOutlivingChain creates a 30-day delegation on top of a 24-hour proof
+func GenerateOutlivingChain() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	charlie, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bobToCharlie, err := delegation.Delegate(
+		bob,
+		charlie,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(30*24*time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(aliceToBob)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := bobToCharlie.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateDisjointChain creates a delegation that only becomes valid in two
+// days, on top of a proof that expires tomorrow
+func GenerateDisjointChain() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	charlie, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bobToCharlie, err := delegation.Delegate(
+		bob,
+		charlie,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithNotBefore(int(time.Now().Add(48*time.Hour).Unix())),
+		delegation.WithExpiration(int(time.Now().Add(72*time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(aliceToBob)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := bobToCharlie.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateChainWithoutProofs creates a delegation whose proof block is left
+// out of the exported CAR
+func GenerateChainWithoutProofs() ([]byte, error) {
+	chainBytes, err := GenerateComplexChain()
+	if err != nil {
+		return nil, err
+	}
+
+	del, err := delegation.Extract(chainBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuild the delegation over a block reader holding only its root block
+	br, err := blockstore.NewBlockReader(blockstore.WithBlocks([]ipld.Block{del.Root()}))
+	if err != nil {
+		return nil, err
+	}
+	stripped, err := delegation.NewDelegation(del.Root(), br)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := stripped.Archive()
+	return io.ReadAll(archive)
+}
+
+// GenerateTamperedCAR creates a chain whose proof block has its ability
+// rewritten from store/* to space/* after export. The block still decodes but
+// no longer hashes to its CID. The proof CID is returned alongside the
+// archive.
+func GenerateTamperedCAR() ([]byte, string, error) {
+	chainBytes, err := GenerateComplexChain()
+	if err != nil {
+		return nil, "", err
+	}
+
+	del, err := delegation.Extract(chainBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	proof := del.Proofs()[0]
+
+	for blk, err := range del.Blocks() {
+		if err != nil {
+			return nil, "", err
+		}
+		if blk.Link().String() != proof.String() {
+			continue
+		}
+		at := bytes.Index(chainBytes, blk.Bytes())
+		if at < 0 {
+			break
+		}
+		section := chainBytes[at : at+len(blk.Bytes())]
+		ability := bytes.Index(section, []byte("store/*"))
+		if ability < 0 {
+			break
+		}
+		copy(section[ability:], "space/*")
+		return chainBytes, proof.String(), nil
+	}
+
+	return nil, "", fmt.Errorf("proof block not found in archive")
+}
+
+// GenerateSelfReferencingCARv2 creates a 51 byte CARv2: the pragma and a
+// header whose payload is the whole archive, pragma included, rather than
+// a CARv1 following the header
+func GenerateSelfReferencingCARv2() []byte {
+	pragma := []byte{0x0a, 0xa1, 0x67, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x02}
+	archive := make([]byte, len(pragma)+40)
+	copy(archive, pragma)
+	header := archive[len(pragma):]
+	binary.LittleEndian.PutUint64(header[16:24], 0)
+	binary.LittleEndian.PutUint64(header[24:32], uint64(len(archive)))
+	return archive
+}
+
+// GenerateBundleCAR creates a CAR with two roots sharing Alice as issuer:
+// the ucanto archive of Bob->Dave (proven by Alice->Bob), and the
+// Alice->Carol delegation block itself
+func GenerateBundleCAR() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+	carol, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+	dave, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/*", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(7*24*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bobToDave, err := delegation.Delegate(
+		bob,
+		dave,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(48*time.Hour).Unix())),
+		delegation.WithProof(delegation.FromDelegation(aliceToBob)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	aliceToCarol, err := delegation.Delegate(
+		alice,
+		carol,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("upload/*", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		delegation.WithExpiration(int(time.Now().Add(48*time.Hour).Unix())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := io.ReadAll(bobToDave.Archive())
+	if err != nil {
+		return nil, err
+	}
+	archiveRoots, archiveBlocks, err := car.Decode(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+
+	roots := []ipld.Link{archiveRoots[0], aliceToCarol.Link()}
+	return io.ReadAll(car.Encode(roots, func(yield func(ipld.Block, error) bool) {
+		for blk, err := range archiveBlocks {
+			if !yield(blk, err) || err != nil {
+				return
+			}
+		}
+		for blk, err := range aliceToCarol.Export() {
+			if !yield(blk, err) || err != nil {
+				return
+			}
+		}
+	}))
+}
+
+// GenerateDiamondChain creates a delegation citing two proofs that both rest
+// on the same root proof:
+//
+//	Carol->Dave cites [Bob->Carol (upload), Bob->Carol (store)]
+//	both Bob->Carol delegations cite Alice->Bob
+func GenerateDiamondChain() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	carol, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	dave, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := delegation.WithExpiration(int(time.Now().Add(24 * time.Hour).Unix()))
+
+	aliceToBob, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("*", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		exp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var middle []delegation.Proof
+	for _, can := range []string{"upload/add", "store/add"} {
+		bobToCarol, err := delegation.Delegate(
+			bob,
+			carol,
+			[]ucan.Capability[ucan.NoCaveats]{
+				ucan.NewCapability(can, "storage:alice/*", ucan.NoCaveats{}),
+			},
+			exp,
+			delegation.WithProof(delegation.FromDelegation(aliceToBob)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		middle = append(middle, delegation.FromDelegation(bobToCarol))
+	}
+
+	carolToDave, err := delegation.Delegate(
+		carol,
+		dave,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("upload/add", "storage:alice/*", ucan.NoCaveats{}),
+			ucan.NewCapability("store/add", "storage:alice/*", ucan.NoCaveats{}),
+		},
+		exp,
+		delegation.WithProof(middle...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := carolToDave.Archive()
+	return io.ReadAll(archive)
+}
+
+// issueUploadAdd has Bob invoke upload/add on Alice's space, proven by a
+// delegation from Alice, and a service sign a successful receipt for it
+func issueUploadAdd() (invocation.Invocation, receipt.AnyReceipt, error) {
+	inv, service, err := invokeUploadAdd()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rcpt, err := receipt.Issue(service, result.Ok[ok.Unit, ipld.Builder](ok.Unit{}), ran.FromInvocation(inv))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return inv, rcpt, nil
+}
+
+// invokeUploadAdd has Bob invoke upload/add on Alice's space, proven by a
+// delegation from Alice, and returns the invocation with its audience
+func invokeUploadAdd() (invocation.Invocation, principal.Signer, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service, err := signer.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exp := delegation.WithExpiration(int(time.Now().Add(24 * time.Hour).Unix()))
+
+	proof, err := delegation.Delegate(
+		alice,
+		bob,
+		[]ucan.Capability[ucan.NoCaveats]{
+			ucan.NewCapability("upload/*", alice.DID().String(), ucan.NoCaveats{}),
+		},
+		exp,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err := cid.Parse("bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	if err != nil {
+		return nil, nil, err
+	}
+	nb, err := qp.BuildMap(basicnode.Prototype.Any, 1, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "root", qp.Link(cidlink.Link{Cid: root}))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inv, err := invocation.Invoke(
+		bob,
+		service,
+		ucan.NewCapability("upload/add", alice.DID().String(), nodeCaveats{node: nb}),
+		exp,
+		delegation.WithProof(delegation.FromDelegation(proof)),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return inv, service, nil
+}
+
+// GenerateAgentMessage creates a ucanto agent message executing an
+// upload/add invocation and reporting its receipt
+func GenerateAgentMessage() ([]byte, error) {
+	inv, rcpt, err := issueUploadAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := message.Build([]invocation.Invocation{inv}, []receipt.AnyReceipt{rcpt})
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(car.Encode([]ipld.Link{msg.Root().Link()}, msg.Blocks()))
+}
+
+// GenerateReceipt creates a receipt archive that embeds the upload/add
+// invocation it ran
+func GenerateReceipt() ([]byte, error) {
+	_, rcpt, err := issueUploadAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(rcpt.Archive())
+}
+
+// ed25519Varsig is the varsig header for an Ed25519 signature over a
+// DAG-CBOR payload: prefix 0x34, ed25519-pub 0xed, dag-cbor 0x71
+var ed25519Varsig = []byte{0x34, 0xed, 0x01, 0x71}
+
+// signEnvelope builds and signs a UCAN 1.0 envelope:
+// [signature, {"h": varsig header, tag: payload}]
+func signEnvelope(issuer ucan.Signer, tag string, size int64, payload func(datamodel.MapAssembler)) ([]byte, error) {
+	signed, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "h", qp.Bytes(ed25519Varsig))
+		qp.MapEntry(ma, tag, qp.Map(size, payload))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var signingInput bytes.Buffer
+	if err := dagcbor.Encode(signed, &signingInput); err != nil {
+		return nil, err
+	}
+	sig := issuer.Sign(signingInput.Bytes())
+
+	envelope, err := qp.BuildList(basicnode.Prototype.Any, 2, func(la datamodel.ListAssembler) {
+		qp.ListEntry(la, qp.Bytes(sig.Raw()))
+		qp.ListEntry(la, qp.Node(signed))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := dagcbor.Encode(envelope, &out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// GenerateInvocationEnvelope creates a signed UCAN 1.0 invocation envelope
+func GenerateInvocationEnvelope() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := time.Now().Add(24 * time.Hour).Unix()
+	return signEnvelope(alice, "ucan/inv@1.0.0-rc.1", 8, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "iss", qp.String(alice.DID().String()))
+		qp.MapEntry(ma, "sub", qp.String(alice.DID().String()))
+		qp.MapEntry(ma, "aud", qp.String(bob.DID().String()))
+		qp.MapEntry(ma, "cmd", qp.String("/blob/add"))
+		qp.MapEntry(ma, "args", qp.Map(1, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "size", qp.Int(1024))
+		}))
+		qp.MapEntry(ma, "nonce", qp.Bytes([]byte{1, 2, 3, 4}))
+		qp.MapEntry(ma, "exp", qp.Int(exp))
+		qp.MapEntry(ma, "prf", qp.List(0, func(datamodel.ListAssembler) {}))
+	})
+}
+
+// GenerateDelegationEnvelope creates a signed UCAN 1.0 delegation of /blob
+// from Alice to Bob, restricted by a policy, with metadata and iat
+func GenerateDelegationEnvelope() ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return signEnvelope(alice, "ucan/dlg@1.0.0-rc.1", 10, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "iss", qp.String(alice.DID().String()))
+		qp.MapEntry(ma, "aud", qp.String(bob.DID().String()))
+		qp.MapEntry(ma, "sub", qp.String(alice.DID().String()))
+		qp.MapEntry(ma, "cmd", qp.String("/blob"))
+		qp.MapEntry(ma, "pol", qp.List(2, func(la datamodel.ListAssembler) {
+			qp.ListEntry(la, qp.List(3, func(la datamodel.ListAssembler) {
+				qp.ListEntry(la, qp.String("<="))
+				qp.ListEntry(la, qp.String(".size"))
+				qp.ListEntry(la, qp.Int(1<<20))
+			}))
+			qp.ListEntry(la, qp.List(3, func(la datamodel.ListAssembler) {
+				qp.ListEntry(la, qp.String("like"))
+				qp.ListEntry(la, qp.String(".type"))
+				qp.ListEntry(la, qp.String("image/*"))
+			}))
+		}))
+		qp.MapEntry(ma, "meta", qp.Map(1, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "note", qp.String("avatar uploads"))
+		}))
+		qp.MapEntry(ma, "nonce", qp.Bytes([]byte{5, 6, 7, 8}))
+		qp.MapEntry(ma, "nbf", qp.Int(now.Add(-time.Hour).Unix()))
+		qp.MapEntry(ma, "exp", qp.Int(now.Add(48*time.Hour).Unix()))
+		qp.MapEntry(ma, "iat", qp.Int(now.Unix()))
+	})
+}
+
+// GeneratePolicyContainer creates a UCAN 1.0 container holding a delegation
+// of /blob from Alice to Bob under a policy, and Bob's /blob/add invocation
+// with the given size and type arguments
+func GeneratePolicyContainer(size int64, mime string) ([]byte, error) {
+	alice, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	bob, err := signer.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := time.Now().Add(24 * time.Hour).Unix()
+	dlg, err := signEnvelope(alice, "ucan/dlg@1.0.0-rc.1", 8, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "iss", qp.String(alice.DID().String()))
+		qp.MapEntry(ma, "aud", qp.String(bob.DID().String()))
+		qp.MapEntry(ma, "sub", qp.String(alice.DID().String()))
+		qp.MapEntry(ma, "cmd", qp.String("/blob"))
+		qp.MapEntry(ma, "pol", qp.List(3, func(la datamodel.ListAssembler) {
+			qp.ListEntry(la, qp.List(2, func(la datamodel.ListAssembler) {
+				qp.ListEntry(la, qp.String("and"))
+				qp.ListEntry(la, qp.List(2, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.List(3, func(la datamodel.ListAssembler) {
+						qp.ListEntry(la, qp.String("<="))
+						qp.ListEntry(la, qp.String(".size"))
+						qp.ListEntry(la, qp.Int(1<<20))
+					}))
+					qp.ListEntry(la, qp.List(3, func(la datamodel.ListAssembler) {
+						qp.ListEntry(la, qp.String("like"))
+						qp.ListEntry(la, qp.String(".type"))
+						qp.ListEntry(la, qp.String("image/*"))
+					}))
+				}))
+			}))
+			qp.ListEntry(la, qp.List(3, func(la datamodel.ListAssembler) {
+				qp.ListEntry(la, qp.String("any"))
+				qp.ListEntry(la, qp.String(".tags"))
+				qp.ListEntry(la, qp.List(3, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("=="))
+					qp.ListEntry(la, qp.String("."))
+					qp.ListEntry(la, qp.String("avatar"))
+				}))
+			}))
+			qp.ListEntry(la, qp.List(2, func(la datamodel.ListAssembler) {
+				qp.ListEntry(la, qp.String("not"))
+				qp.ListEntry(la, qp.List(3, func(la datamodel.ListAssembler) {
+					qp.ListEntry(la, qp.String("=="))
+					qp.ListEntry(la, qp.String(".meta?.private"))
+					qp.ListEntry(la, qp.Bool(true))
+				}))
+			}))
+		}))
+		qp.MapEntry(ma, "nonce", qp.Bytes([]byte{9, 10, 11, 12}))
+		qp.MapEntry(ma, "exp", qp.Int(exp))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dlgCID, err := cid.Prefix{Version: 1, Codec: cid.DagCBOR, MhType: multihash.SHA2_256, MhLength: -1}.Sum(dlg)
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := signEnvelope(bob, "ucan/inv@1.0.0-rc.1", 8, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "iss", qp.String(bob.DID().String()))
+		qp.MapEntry(ma, "sub", qp.String(alice.DID().String()))
+		qp.MapEntry(ma, "cmd", qp.String("/blob/add"))
+		qp.MapEntry(ma, "args", qp.Map(3, func(ma datamodel.MapAssembler) {
+			qp.MapEntry(ma, "size", qp.Int(size))
+			qp.MapEntry(ma, "type", qp.String(mime))
+			qp.MapEntry(ma, "tags", qp.List(2, func(la datamodel.ListAssembler) {
+				qp.ListEntry(la, qp.String("profile"))
+				qp.ListEntry(la, qp.String("avatar"))
+			}))
+		}))
+		qp.MapEntry(ma, "nonce", qp.Bytes([]byte{13, 14, 15, 16}))
+		qp.MapEntry(ma, "exp", qp.Int(exp))
+		qp.MapEntry(ma, "prf", qp.List(1, func(la datamodel.ListAssembler) {
+			qp.ListEntry(la, qp.Link(cidlink.Link{Cid: dlgCID}))
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := qp.BuildMap(basicnode.Prototype.Any, 1, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "ctn-v1", qp.List(2, func(la datamodel.ListAssembler) {
+			qp.ListEntry(la, qp.Bytes(dlg))
+			qp.ListEntry(la, qp.Bytes(inv))
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := dagcbor.Encode(container, &out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// GenerateW3DelegationOutput mimics `w3 delegation create --base64`: the
+// delegation CAR wrapped in a CID with an identity multihash, printed as
+// multibase base64
+func GenerateW3DelegationOutput() (string, error) {
+	archive, err := GenerateValidUCAN()
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := multihash.Sum(archive, multihash.IDENTITY, -1)
+	if err != nil {
+		return "", err
+	}
+
+	return cid.NewCidV1(uint64(multicodec.Car), digest).Encode(multibase.MustNewEncoder(multibase.Base64)), nil
+}
+
+// GenerateFailedReceipt creates a receipt reporting an upload/add error with
+// a forked and a joined effect and some metadata
+func GenerateFailedReceipt() ([]byte, error) {
+	inv, service, err := invokeUploadAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	failure, err := qp.BuildMap(basicnode.Prototype.Any, 2, func(ma datamodel.MapAssembler) {
+		qp.MapEntry(ma, "name", qp.String("ShardNotFound"))
+		qp.MapEntry(ma, "message", qp.String("shard was never stored"))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fork, err := cid.Parse("bafyreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy")
+	if err != nil {
+		return nil, err
+	}
+	join, err := cid.Parse("bafkreiem4twkqzsq2aj4shbycd4yvoj2cx72vezicletlhi7dijjciqpui")
+	if err != nil {
+		return nil, err
+	}
+
+	retries := int64(3)
+	rcpt, err := receipt.Issue(
+		service,
+		result.Error[ipld.Builder, ipld.Builder](nodeCaveats{node: failure}),
+		ran.FromInvocation(inv),
+		receipt.WithFork(fx.FromLink(cidlink.Link{Cid: fork})),
+		receipt.WithJoin(fx.FromLink(cidlink.Link{Cid: join})),
+		receipt.WithMeta(map[string]any{"retries": &retries}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(rcpt.Archive())
+}
+
+// GenerateDetachedReceipt creates a receipt that links to its invocation
+// without including it
+func GenerateDetachedReceipt() ([]byte, error) {
+	inv, service, err := invokeUploadAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	rcpt, err := receipt.Issue(service, result.Ok[ok.Unit, ipld.Builder](ok.Unit{}), ran.FromLink(inv.Link()))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(rcpt.Archive())
+}
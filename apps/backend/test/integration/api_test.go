@@ -3,16 +3,27 @@ package integration
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image/png"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 	"github.com/goddhi/ucan-visualizer/internal/api"
 	"github.com/goddhi/ucan-visualizer/internal/models"
+	"github.com/goddhi/ucan-visualizer/internal/services/validator"
 	"github.com/goddhi/ucan-visualizer/test/fixtures"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseEndpoint(t *testing.T) {
@@ -243,4 +254,2136 @@ func TestHealthEndpoint(t *testing.T) {
 
 	assert.Equal(t, "healthy", result["status"])
 	t.Logf("✅ Health check passed")
-}
\ No newline at end of file
+}
+
+func TestSignatureVerification(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("Valid CAR delegation signature", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateValidUCAN()
+		require.NoError(t, err)
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, "EdDSA", result.Signature.Algorithm)
+		assert.True(t, result.Signature.Verified)
+		assert.True(t, result.Signature.Valid)
+		assert.Empty(t, result.Signature.Error)
+	})
+
+	t.Run("Valid RSA CAR delegation signature", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateRSAUCAN()
+		require.NoError(t, err)
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, strings.HasPrefix(result.Issuer, "did:key:z4MX"), result.Issuer)
+		assert.Equal(t, "RS256", result.Signature.Algorithm)
+		assert.True(t, result.Signature.Verified)
+		assert.True(t, result.Signature.Valid)
+		assert.Empty(t, result.Signature.Error)
+	})
+
+	t.Run("Forged CAR delegation is rejected", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateForgedUCAN()
+		require.NoError(t, err)
+
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "invalid_signature", result.RootCause.Type)
+	})
+
+	t.Run("Valid JWT signature", func(t *testing.T) {
+		token, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: token,
+		}, &result)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, "EdDSA", result.Signature.Algorithm)
+		assert.True(t, result.Signature.Verified)
+		assert.True(t, result.Signature.Valid)
+	})
+
+	t.Run("Valid RSA JWT signature", func(t *testing.T) {
+		token, err := fixtures.GenerateRSAJWTUCAN()
+		require.NoError(t, err)
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: token,
+		}, &result)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, "RS256", result.Signature.Algorithm)
+		assert.True(t, result.Signature.Verified)
+		assert.True(t, result.Signature.Valid)
+	})
+
+	t.Run("Tampered RSA JWT signature", func(t *testing.T) {
+		token, err := fixtures.GenerateRSAJWTUCAN()
+		require.NoError(t, err)
+		other, err := fixtures.GenerateRSAJWTUCAN()
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		tampered := parts[0] + "." + parts[1] + "." + strings.Split(other, ".")[2]
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: tampered,
+		}, &result)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Signature.Verified)
+		assert.False(t, result.Signature.Valid)
+	})
+
+	t.Run("Tampered JWT signature", func(t *testing.T) {
+		token, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+
+		// Swap the signature for one from a different token
+		other, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+		parts := strings.Split(token, ".")
+		otherParts := strings.Split(other, ".")
+		tampered := parts[0] + "." + parts[1] + "." + otherParts[2]
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: tampered,
+		}, &result)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Signature.Verified)
+		assert.False(t, result.Signature.Valid)
+		assert.NotEmpty(t, result.Signature.Error)
+	})
+}
+
+// postJSON sends a JSON request and decodes the JSON response into out
+func postJSON(t *testing.T, url string, payload interface{}, out interface{}) *http.Response {
+	t.Helper()
+
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+func TestCaveatDecoding(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tokenBytes, err := fixtures.GenerateBlobAddUCAN()
+	require.NoError(t, err)
+
+	// Decode generically to inspect the DAG-JSON shapes on the wire
+	var result map[string]interface{}
+	resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+		Token: base64.StdEncoding.EncodeToString(tokenBytes),
+	}, &result)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	caps := result["capabilities"].([]interface{})
+	require.Len(t, caps, 1)
+	nb := caps[0].(map[string]interface{})["nb"].(map[string]interface{})
+
+	blob, ok := nb["blob"].(map[string]interface{})
+	require.True(t, ok, "nested caveat map should be decoded, got %v", nb["blob"])
+	assert.Equal(t, float64(1024), blob["size"])
+	assert.Equal(t, map[string]interface{}{
+		"/": map[string]interface{}{"bytes": "EiDerb7v"},
+	}, blob["digest"])
+
+	assert.Equal(t, map[string]interface{}{
+		"/": "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy",
+	}, nb["root"])
+}
+
+func TestPrincipalAlignment(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("Aligned chain", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Valid)
+		for _, link := range result.Chain {
+			for _, issue := range link.Issues {
+				assert.NotEqual(t, "principal_misaligned", issue.Type)
+			}
+		}
+	})
+
+	t.Run("Misaligned chain", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateMisalignedChain()
+		require.NoError(t, err)
+
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "principal_misaligned", result.RootCause.Type)
+
+		require.Len(t, result.Chain, 2)
+		child, proof := result.Chain[0], result.Chain[1]
+		require.NotNil(t, result.RootCause.Link)
+		assert.Equal(t, child.CID, result.RootCause.Link.CID)
+		assert.Contains(t, result.RootCause.Message, child.Issuer)
+		assert.Contains(t, result.RootCause.Message, proof.Audience)
+		assert.True(t, proof.Valid, "the proof itself is well-formed")
+	})
+}
+
+func TestCapabilityAttenuation(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("Properly attenuated chain", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Valid)
+		require.NotEmpty(t, result.Chain)
+		require.Len(t, result.Chain[0].ProofValidation, 1)
+
+		pv := result.Chain[0].ProofValidation[0]
+		assert.Equal(t, result.Chain[1].CID, pv.ProofCID)
+		assert.True(t, pv.Valid)
+		assert.True(t, pv.Attenuation.ResourceMatch)
+		assert.True(t, pv.Attenuation.AbilityMatch)
+		assert.True(t, pv.Attenuation.CaveatProperlyAdded)
+	})
+
+	t.Run("Escalated ability", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateEscalatedChain()
+		require.NoError(t, err)
+
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "attenuation_violation", result.RootCause.Type)
+
+		require.Len(t, result.Chain[0].ProofValidation, 1)
+		pv := result.Chain[0].ProofValidation[0]
+		assert.False(t, pv.Valid)
+		assert.True(t, pv.Attenuation.ResourceMatch)
+		assert.False(t, pv.Attenuation.AbilityMatch)
+		assert.NotEmpty(t, pv.Attenuation.Issues)
+	})
+
+	t.Run("Self-issued capability with a proof attached", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateSelfIssuedWithProof()
+		require.NoError(t, err)
+
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Valid)
+		require.Len(t, result.Chain, 2)
+		assert.Empty(t, result.Chain[0].ProofValidation)
+	})
+
+	t.Run("Numeric caveats", func(t *testing.T) {
+		for _, tc := range []struct {
+			name       string
+			key        string
+			childValue int64
+			valid      bool
+			trace      string
+		}{
+			{"exact size kept", "size", 1024, true, "unchanged"},
+			{"exact size lowered", "size", 512, false, "broadened"},
+			{"exact size raised", "size", 2048, false, "broadened"},
+			{"limit lowered", "maxSize", 512, true, "attenuated"},
+			{"limit raised", "maxSize", 2048, false, "broadened"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				tokenBytes, err := fixtures.GenerateCaveatChain(tc.key, 1024, tc.childValue)
+				require.NoError(t, err)
+				token := base64.StdEncoding.EncodeToString(tokenBytes)
+
+				var result models.ValidationResult
+				resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{Token: token}, &result)
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+
+				assert.Equal(t, tc.valid, result.Valid)
+				require.Len(t, result.Chain[0].ProofValidation, 1)
+				assert.Equal(t, tc.valid, result.Chain[0].ProofValidation[0].Attenuation.CaveatProperlyAdded)
+
+				var trace models.CapabilityTrace
+				resp = postJSON(t, server.URL+"/api/graph/trace", models.TraceRequest{Token: token, Can: "space/blob/add"}, &trace)
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				require.Len(t, trace.Paths, 1)
+				require.Len(t, trace.Paths[0].Hops, 2)
+				assert.Equal(t, tc.trace, trace.Paths[0].Hops[1].Status)
+				assert.Equal(t, tc.valid, trace.Granted)
+			})
+		}
+	})
+}
+
+func TestTimeBoundContainment(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tokenBytes, err := fixtures.GenerateOutlivingChain()
+	require.NoError(t, err)
+
+	var result models.ValidationResult
+	resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+		Token: base64.StdEncoding.EncodeToString(tokenBytes),
+	}, &result)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, result.Chain, 2)
+
+	child, parent := result.Chain[0], result.Chain[1]
+
+	var outlives bool
+	for _, issue := range child.Issues {
+		if issue.Type == "outlives_proof" {
+			outlives = true
+			assert.Equal(t, "warning", issue.Severity)
+		}
+	}
+	assert.True(t, outlives, "child outliving its proof should be flagged")
+
+	require.NotNil(t, result.ValidityWindow)
+	assert.True(t, result.ValidityWindow.Expiration.Equal(parent.Expiration))
+	assert.Equal(t, parent.CID, result.ValidityWindow.ExpirationCID)
+	assert.False(t, result.ValidityWindow.Empty)
+}
+
+func TestEffectiveValidityWindow(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	issueTypes := func(link models.ChainLink) map[string]string {
+		types := make(map[string]string)
+		for _, issue := range link.Issues {
+			types[issue.Type] = issue.Severity
+		}
+		return types
+	}
+
+	t.Run("Windows that never overlap", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateDisjointChain()
+		require.NoError(t, err)
+
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, result.Chain, 2)
+
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.ValidityWindow)
+		assert.True(t, result.ValidityWindow.Empty)
+		assert.Equal(t, "error", issueTypes(result.Chain[0])["empty_validity_window"])
+	})
+
+	t.Run("Proof expired before the evaluation time", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateOutlivingChain()
+		require.NoError(t, err)
+
+		at := time.Now().Add(10 * 24 * time.Hour)
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+			At:    &at,
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, result.Chain, 2)
+
+		child, parent := result.Chain[0], result.Chain[1]
+		assert.False(t, result.Valid)
+		assert.False(t, child.Valid)
+		assert.NotContains(t, issueTypes(child), "expired")
+		assert.Equal(t, "error", issueTypes(child)["outside_validity_window"])
+		assert.Equal(t, "error", issueTypes(parent)["expired"])
+		assert.NotContains(t, issueTypes(parent), "outside_validity_window")
+	})
+}
+
+func TestValidateAsOf(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("Expired delegation was valid in the past", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateExpiredUCAN()
+		require.NoError(t, err)
+
+		at := time.Now().Add(-36 * time.Hour)
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+			At:    &at,
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Valid)
+		assert.True(t, result.EvaluatedAt.Equal(at))
+	})
+
+	t.Run("Valid delegation will have expired in the future", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateValidUCAN()
+		require.NoError(t, err)
+
+		at := time.Now().Add(48 * time.Hour)
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+			At:    &at,
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "expired", result.RootCause.Type)
+	})
+
+	t.Run("Configurable expiring soon window", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateValidUCAN()
+		require.NoError(t, err)
+		token := base64.StdEncoding.EncodeToString(tokenBytes)
+
+		for window, expectWarning := range map[string]bool{"48h": true, "1h": false} {
+			var result models.ValidationResult
+			resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+				Token:        token,
+				ExpiringSoon: window,
+			}, &result)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var warned bool
+			for _, issue := range result.Chain[0].Issues {
+				warned = warned || issue.Type == "expiring_soon"
+			}
+			assert.Equal(t, expectWarning, warned, "window %s", window)
+		}
+	})
+
+	t.Run("Invalid expiring soon window", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateValidUCAN()
+		require.NoError(t, err)
+
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token:        base64.StdEncoding.EncodeToString(tokenBytes),
+			ExpiringSoon: "soon",
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Injected clock", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateExpiredUCAN()
+		require.NoError(t, err)
+
+		past := time.Now().Add(-36 * time.Hour)
+		svc := validator.NewServiceWithClock(func() time.Time { return past })
+
+		result, err := svc.ValidateChain(tokenBytes)
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.True(t, result.EvaluatedAt.Equal(past))
+	})
+}
+
+func TestMissingProofs(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tokenBytes, err := fixtures.GenerateChainWithoutProofs()
+	require.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(tokenBytes)
+
+	t.Run("Validation reports missing proof", func(t *testing.T) {
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: token,
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "missing_proof", result.RootCause.Type)
+	})
+
+	t.Run("Graph marks chain incomplete", func(t *testing.T) {
+		var result models.GraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{
+			Token: token,
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, result.Chain.IsComplete)
+		require.Len(t, result.Chain.UnresolvedProofs, 1)
+		missing := result.Chain.UnresolvedProofs[0]
+		assert.Equal(t, result.Chain.RootCID, missing.CitedBy)
+		assert.Equal(t, 0, missing.Index)
+
+		require.NotNil(t, result.Chain.ProofChain)
+		assert.False(t, result.Chain.ProofChain.IsComplete)
+
+		var placeholder *models.GraphNode
+		for i, node := range result.Nodes {
+			if node.Type == "unresolved" {
+				placeholder = &result.Nodes[i]
+			}
+		}
+		require.NotNil(t, placeholder, "missing proof should render as a placeholder node")
+		assert.Equal(t, "proof-"+missing.CID, placeholder.ID)
+	})
+
+	t.Run("Complete chain", func(t *testing.T) {
+		chainBytes, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var result models.GraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{
+			Token: base64.StdEncoding.EncodeToString(chainBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Chain.IsComplete)
+		assert.Empty(t, result.Chain.UnresolvedProofs)
+	})
+}
+
+func TestDelegationDAG(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tokenBytes, err := fixtures.GenerateDiamondChain()
+	require.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(tokenBytes)
+
+	t.Run("Shared proof parsed once", func(t *testing.T) {
+		var dag models.DelegationDAG
+		resp := postJSON(t, server.URL+"/api/parse/chain", models.ParseRequest{
+			Token: token,
+		}, &dag)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Len(t, dag.Delegations, 4)
+		assert.Len(t, dag.Order, 4)
+		assert.Len(t, dag.Edges, 4)
+		assert.Empty(t, dag.Unresolved)
+		assert.Equal(t, dag.Root, dag.Order[0])
+
+		// Both middle delegations point at the same shared proof
+		root := dag.Delegations[dag.Root]
+		require.Len(t, root.Proofs, 2)
+		shared := dag.Delegations[root.Proofs[0].CID].Proofs[0].CID
+		assert.Equal(t, shared, dag.Delegations[root.Proofs[1].CID].Proofs[0].CID)
+		assert.Equal(t, 2, dag.Delegations[shared].Level)
+
+		var citations int
+		for _, edge := range dag.Edges {
+			if edge.Proof == shared {
+				citations++
+			}
+		}
+		assert.Equal(t, 2, citations)
+	})
+
+	t.Run("Validation visits each delegation once", func(t *testing.T) {
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: token,
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Valid)
+		assert.Equal(t, 4, result.Summary.TotalLinks)
+	})
+
+	t.Run("Graph draws one proof edge per citation", func(t *testing.T) {
+		var result models.GraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{
+			Token: token,
+			Mode:  "delegations",
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var proofEdges int
+		for _, edge := range result.Edges {
+			if edge.Type == "proves" {
+				proofEdges++
+			}
+		}
+		assert.Equal(t, 4, proofEdges)
+		assert.True(t, result.Chain.IsComplete)
+		assert.Len(t, result.Chain.LeafCIDs, 1)
+	})
+
+	t.Run("Principal graph edges join principals", func(t *testing.T) {
+		var result models.GraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{
+			Token: token,
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		ids := make(map[string]bool)
+		for _, node := range result.Nodes {
+			ids[node.ID] = true
+		}
+		for _, edge := range result.Edges {
+			assert.NotEqual(t, "proof", edge.Type)
+			assert.True(t, ids[edge.Source], "edge source %s is a node", edge.Source)
+			assert.True(t, ids[edge.Target], "edge target %s is a node", edge.Target)
+		}
+	})
+}
+
+func TestInvocationDetection(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	parseInvocation := func(t *testing.T, tokenBytes []byte) models.InvocationResponse {
+		var result models.InvocationResponse
+		resp := postJSON(t, server.URL+"/api/parse/invocation", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return result
+	}
+
+	t.Run("Agent message", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateAgentMessage()
+		require.NoError(t, err)
+
+		result := parseInvocation(t, tokenBytes)
+		assert.True(t, result.IsInvocation)
+		assert.Equal(t, "agent_message", result.InvocationAnalysis.Source)
+
+		require.NotNil(t, result.Task)
+		assert.Equal(t, "upload/add", result.Task.Action)
+		assert.Equal(t, result.Delegation.CID, result.Task.CID)
+		assert.NotEmpty(t, result.Task.ReceiptCID)
+		assert.Contains(t, result.Task.Constraints, "root")
+		assert.Equal(t, 1, result.CapabilityAnalysis.InvokeCount)
+
+		// The proof travels inside the message
+		require.Len(t, result.Delegation.Proofs, 1)
+		assert.True(t, result.Delegation.Proofs[0].Resolved)
+		assert.True(t, result.Delegation.Signature.Valid)
+	})
+
+	t.Run("Receipt ran link", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateReceipt()
+		require.NoError(t, err)
+
+		result := parseInvocation(t, tokenBytes)
+		assert.True(t, result.IsInvocation)
+		assert.Equal(t, "receipt", result.InvocationAnalysis.Source)
+		require.NotNil(t, result.Task)
+		assert.Equal(t, "upload/add", result.Task.Action)
+		assert.NotEmpty(t, result.Task.ReceiptCID)
+	})
+
+	t.Run("UCAN 1.0 invocation envelope", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateInvocationEnvelope()
+		require.NoError(t, err)
+
+		result := parseInvocation(t, tokenBytes)
+		assert.True(t, result.IsInvocation)
+		assert.Equal(t, "ucan_envelope", result.InvocationAnalysis.Source)
+		require.NotNil(t, result.Task)
+		assert.Equal(t, "/blob/add", result.Task.Action)
+		assert.EqualValues(t, 1024, result.Task.Constraints["size"])
+		assert.True(t, result.Delegation.Signature.Valid)
+	})
+
+	t.Run("Delegation between different principals", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateValidUCAN()
+		require.NoError(t, err)
+
+		result := parseInvocation(t, tokenBytes)
+		assert.False(t, result.IsInvocation)
+		assert.Nil(t, result.Task)
+		assert.Equal(t, "delegation", result.InvocationAnalysis.Source)
+		assert.Equal(t, 0, result.CapabilityAnalysis.InvokeCount)
+	})
+
+	t.Run("Graph follows the invocation chain", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateAgentMessage()
+		require.NoError(t, err)
+
+		var result models.InvocationGraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/invocation", models.GraphRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.IsInvocation)
+		assert.True(t, result.Chain.IsComplete)
+
+		var invocationEdges int
+		for _, edge := range result.Edges {
+			if edge.Type == "invocation" {
+				invocationEdges++
+			}
+		}
+		assert.Equal(t, 1, invocationEdges)
+	})
+}
+
+func TestReceipts(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	parseReceipt := func(t *testing.T, tokenBytes []byte) models.ReceiptResponse {
+		var result models.ReceiptResponse
+		resp := postJSON(t, server.URL+"/api/parse/receipt", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return result
+	}
+
+	t.Run("Successful receipt", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateReceipt()
+		require.NoError(t, err)
+
+		result := parseReceipt(t, tokenBytes)
+		assert.True(t, result.Out.Success)
+		assert.Nil(t, result.Out.Error)
+		assert.Empty(t, result.Fx.Fork)
+
+		require.NotNil(t, result.Ran.Invocation)
+		assert.Equal(t, result.Ran.CID, result.Ran.Invocation.CID)
+		assert.Equal(t, result.Ran.Invocation.Audience, result.Issuer)
+
+		assert.True(t, result.Signature.Verified)
+		assert.True(t, result.Signature.Valid)
+	})
+
+	t.Run("Failed receipt with effects", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateFailedReceipt()
+		require.NoError(t, err)
+
+		result := parseReceipt(t, tokenBytes)
+		assert.False(t, result.Out.Success)
+		failure, ok := result.Out.Error.(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "ShardNotFound", failure["name"])
+
+		assert.Len(t, result.Fx.Fork, 1)
+		assert.NotEmpty(t, result.Fx.Join)
+		assert.EqualValues(t, 3, result.Meta["retries"])
+		assert.True(t, result.Signature.Valid)
+	})
+
+	t.Run("Graph links receipt, invocation and chain", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateFailedReceipt()
+		require.NoError(t, err)
+
+		var result models.ReceiptGraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/receipt", models.GraphRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		types := make(map[string]int)
+		for _, node := range result.Nodes {
+			types[node.Type]++
+		}
+		assert.Equal(t, 1, types["receipt"])
+		assert.Equal(t, 1, types["invocation"])
+		assert.Equal(t, 2, types["effect"])
+
+		edgeTypes := make(map[string]int)
+		for _, edge := range result.Edges {
+			edgeTypes[edge.Type]++
+		}
+		assert.Equal(t, 1, edgeTypes["ran"])
+		assert.Equal(t, 1, edgeTypes["invokes"])
+		assert.Equal(t, 1, edgeTypes["issued"])
+		assert.Zero(t, edgeTypes["proof"]) // the proof is resolved
+
+		// Invocation plus the delegation proving it
+		assert.Equal(t, result.Receipt.Ran.CID, result.Chain.RootCID)
+		assert.Equal(t, 2, result.Chain.TotalLevels)
+		assert.True(t, result.Chain.IsComplete)
+	})
+
+	t.Run("Receipt without its invocation", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateDetachedReceipt()
+		require.NoError(t, err)
+
+		result := parseReceipt(t, tokenBytes)
+		assert.Nil(t, result.Ran.Invocation)
+		assert.NotEmpty(t, result.Ran.CID)
+		assert.NotEmpty(t, result.Issuer) // ucanto always sets iss
+
+		var graph models.ReceiptGraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/receipt", models.GraphRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &graph)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var placeholder bool
+		for _, node := range graph.Nodes {
+			if node.Type == "unresolved" && node.ID == "invocation-"+result.Ran.CID {
+				placeholder = true
+			}
+		}
+		assert.True(t, placeholder)
+	})
+
+	t.Run("Delegation is not a receipt", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateValidUCAN()
+		require.NoError(t, err)
+
+		resp := postJSON(t, server.URL+"/api/parse/receipt", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+}
+
+func TestUCAN1Envelopes(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tokenBytes, err := fixtures.GenerateDelegationEnvelope()
+	require.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(tokenBytes)
+
+	t.Run("Parse exposes native fields", func(t *testing.T) {
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.NotNil(t, result.Envelope)
+		env := result.Envelope
+		assert.Equal(t, "delegation", env.Kind)
+		assert.Equal(t, "1.0.0-rc.1", env.Version)
+		assert.Equal(t, "/blob", env.Command)
+		assert.Equal(t, result.Issuer, env.Subject)
+		assert.False(t, env.Powerline)
+		assert.Len(t, env.Policy, 2)
+		assert.Equal(t, "avatar uploads", env.Meta["note"])
+		assert.False(t, env.IssuedAt.IsZero())
+
+		assert.Equal(t, "EdDSA", env.Header.Algorithm)
+		assert.Equal(t, "ed25519-pub", env.Header.KeyType)
+		assert.Equal(t, "dag-cbor", env.Header.PayloadEncoding)
+		assert.Empty(t, env.Header.Error)
+
+		// No capabilities are synthesized from the command or policy
+		assert.Empty(t, result.Capabilities)
+		assert.Empty(t, result.Facts)
+		assert.NotEmpty(t, result.Nonce)
+		assert.True(t, result.Signature.Valid)
+	})
+
+	t.Run("Validate checks commands instead of capabilities", func(t *testing.T) {
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, result.Valid)
+		require.Len(t, result.Chain, 1)
+		assert.Equal(t, "/blob", result.Chain[0].Command)
+		for _, issue := range result.Chain[0].Issues {
+			assert.NotEqual(t, "no_capabilities", issue.Type)
+		}
+	})
+
+	t.Run("Graph labels the command", func(t *testing.T) {
+		var result models.GraphResponse
+		resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, result.Edges, 1)
+		assert.Equal(t, "delegation", result.Edges[0].Type)
+		assert.Equal(t, "/blob", result.Edges[0].Metadata["command"])
+	})
+
+	t.Run("Invocation envelope exposes arguments", func(t *testing.T) {
+		invBytes, err := fixtures.GenerateInvocationEnvelope()
+		require.NoError(t, err)
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(invBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.NotNil(t, result.Envelope)
+		assert.Equal(t, "invocation", result.Envelope.Kind)
+		assert.Equal(t, "/blob/add", result.Envelope.Command)
+		assert.EqualValues(t, 1024, result.Envelope.Args["size"])
+		assert.NotEmpty(t, result.Audience)
+	})
+}
+
+func TestPolicyEvaluation(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	validate := func(t *testing.T, tokenBytes []byte) models.ValidationResult {
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(tokenBytes),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return result
+	}
+
+	t.Run("Arguments within the policy", func(t *testing.T) {
+		tokenBytes, err := fixtures.GeneratePolicyContainer(2048, "image/png")
+		require.NoError(t, err)
+
+		result := validate(t, tokenBytes)
+		assert.True(t, result.Valid, "unexpected issues: %+v", result.Chain)
+		require.Len(t, result.Chain, 2)
+		assert.Equal(t, "/blob/add", result.Chain[0].Command)
+		assert.Equal(t, "/blob", result.Chain[1].Command)
+	})
+
+	t.Run("Failing statement and selected value are reported", func(t *testing.T) {
+		tokenBytes, err := fixtures.GeneratePolicyContainer(2048, "video/mp4")
+		require.NoError(t, err)
+
+		result := validate(t, tokenBytes)
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "policy_violation", result.RootCause.Type)
+
+		var violation *models.ValidationIssue
+		for i, issue := range result.Chain[0].Issues {
+			if issue.Type == "policy_violation" {
+				violation = &result.Chain[0].Issues[i]
+			}
+		}
+		require.NotNil(t, violation)
+		assert.Equal(t, ".type", violation.Context["selector"])
+		assert.Equal(t, "video/mp4", violation.Context["value"])
+		assert.Equal(t, "pol[0][1][1]", violation.Context["path"])
+		assert.Equal(t, []interface{}{"like", ".type", "image/*"}, violation.Context["statement"])
+	})
+
+	t.Run("Numeric comparison", func(t *testing.T) {
+		tokenBytes, err := fixtures.GeneratePolicyContainer(4<<20, "image/png")
+		require.NoError(t, err)
+
+		result := validate(t, tokenBytes)
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "policy_violation", result.RootCause.Type)
+		assert.Contains(t, result.RootCause.Message, `"<="`)
+	})
+}
+
+func TestTokenEncodings(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	archive, err := fixtures.GenerateValidUCAN()
+	require.NoError(t, err)
+	jwt, err := fixtures.GenerateJWTUCAN()
+	require.NoError(t, err)
+	w3Output, err := fixtures.GenerateW3DelegationOutput()
+	require.NoError(t, err)
+
+	base58, err := multibase.Encode(multibase.Base58BTC, archive)
+	require.NoError(t, err)
+	base32, err := multibase.Encode(multibase.Base32, archive)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		token    string
+		format   string
+		encoding string
+	}{
+		{"Base64", base64.StdEncoding.EncodeToString(archive), "", "base64"},
+		{"Base64url without padding", base64.RawURLEncoding.EncodeToString(archive), "auto", "base64url"},
+		{"Hex", hex.EncodeToString(archive), "", "hex"},
+		{"Explicit hex", hex.EncodeToString(archive), "hex", "hex"},
+		{"Multibase base58btc", base58, "auto", "multibase-base58btc"},
+		{"Multibase base32", base32, "", "multibase-base32"},
+		{"w3 delegation create", w3Output, "", "multibase-base64+identity-cid"},
+		{"JWT", jwt, "", "jwt"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result models.DelegationResponse
+			resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+				Token:  tc.token,
+				Format: tc.format,
+			}, &result)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			assert.Equal(t, tc.encoding, resp.Header.Get("X-Token-Encoding"))
+			assert.NotEmpty(t, result.Issuer)
+			assert.True(t, result.Signature.Valid)
+		})
+	}
+
+	t.Run("Wrong explicit format", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{
+			Token:  base64.StdEncoding.EncodeToString(archive),
+			Format: "hex",
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{
+			Token:  base64.StdEncoding.EncodeToString(archive),
+			Format: "base91",
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestRawTokenCIDs(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	parse := func(t *testing.T, token string) models.DelegationResponse {
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return result
+	}
+
+	t.Run("JWT uses the raw codec", func(t *testing.T) {
+		leaf, proof, err := fixtures.GenerateJWTChain()
+		require.NoError(t, err)
+
+		proofResult := parse(t, proof)
+		id, err := cid.Decode(proofResult.CID)
+		require.NoError(t, err)
+		assert.EqualValues(t, cid.Raw, id.Prefix().Codec)
+		assert.EqualValues(t, multihash.SHA2_256, id.Prefix().MhType)
+
+		// The CID matches the one the next JWT cites
+		leafResult := parse(t, leaf)
+		require.Len(t, leafResult.Proofs, 1)
+		assert.Equal(t, proofResult.CID, leafResult.Proofs[0].CID)
+	})
+
+	t.Run("CBOR envelope uses DAG-CBOR", func(t *testing.T) {
+		tokenBytes, err := fixtures.GenerateDelegationEnvelope()
+		require.NoError(t, err)
+
+		result := parse(t, base64.StdEncoding.EncodeToString(tokenBytes))
+		expected, err := cid.Prefix{Version: 1, Codec: cid.DagCBOR, MhType: multihash.SHA2_256, MhLength: -1}.Sum(tokenBytes)
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), result.CID)
+	})
+}
+
+func TestJWTProofChains(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	parseChain := func(t *testing.T, token string) models.DelegationDAG {
+		var dag models.DelegationDAG
+		resp := postJSON(t, server.URL+"/api/parse/chain", models.ParseRequest{Token: token}, &dag)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return dag
+	}
+
+	inline, err := fixtures.GenerateInlineJWTChain()
+	require.NoError(t, err)
+
+	t.Run("Inline proofs parsed recursively", func(t *testing.T) {
+		dag := parseChain(t, inline)
+		require.Len(t, dag.Delegations, 3)
+		assert.Empty(t, dag.Unresolved)
+
+		for level, cid := range dag.Order {
+			del := dag.Delegations[cid]
+			assert.Equal(t, level, del.Level)
+			assert.True(t, del.Signature.Valid)
+			if level < 2 {
+				require.Len(t, del.Proofs, 1)
+				assert.True(t, del.Proofs[0].Inline)
+				assert.True(t, del.Proofs[0].Resolved)
+				assert.Equal(t, dag.Order[level+1], del.Proofs[0].CID)
+			}
+		}
+	})
+
+	t.Run("Inline chain validates and graphs", func(t *testing.T) {
+		var validation models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{Token: inline}, &validation)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, validation.Valid)
+		assert.Equal(t, 3, validation.Summary.TotalLinks)
+
+		var graph models.GraphResponse
+		resp = postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{Token: inline}, &graph)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, graph.Chain.IsComplete)
+		assert.Equal(t, 3, graph.Chain.TotalLevels)
+	})
+
+	leaf, proof, err := fixtures.GenerateJWTChain()
+	require.NoError(t, err)
+
+	t.Run("Bundled proofs resolve by CID", func(t *testing.T) {
+		// The token is whichever JWT no other cites, whatever the order
+		for _, bundle := range []string{leaf + "\n" + proof, proof + " " + leaf} {
+			dag := parseChain(t, bundle)
+			require.Len(t, dag.Delegations, 2)
+			assert.Empty(t, dag.Unresolved)
+
+			root := dag.RootDelegation()
+			require.Len(t, root.Proofs, 1)
+			assert.True(t, root.Proofs[0].Resolved)
+			assert.False(t, root.Proofs[0].Inline)
+			assert.Equal(t, 1, dag.Delegations[root.Proofs[0].CID].Level)
+		}
+	})
+
+	t.Run("JWT whose CID does not match stays unresolved", func(t *testing.T) {
+		unrelated, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+
+		dag := parseChain(t, leaf+"\n"+unrelated)
+		assert.Len(t, dag.Delegations, 1)
+		require.Len(t, dag.Unresolved, 1)
+	})
+}
+
+func TestSpecVersions(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	parse := func(t *testing.T, token string) models.DelegationResponse {
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return result
+	}
+	validate := func(t *testing.T, token string) models.ValidationResult {
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return result
+	}
+	hasIssue := func(link models.ChainLink, issueType string) bool {
+		for _, issue := range link.Issues {
+			if issue.Type == issueType {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("0.9 JWT header", func(t *testing.T) {
+		jwt, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+
+		result := parse(t, jwt)
+		assert.Equal(t, "0.9", result.Version)
+		assert.False(t, result.VersionInferred)
+		require.NotNil(t, result.Header)
+		assert.Equal(t, "EdDSA", result.Header.Alg)
+		assert.Equal(t, "JWT", result.Header.Typ)
+		assert.Equal(t, "0.9.1", result.Header.Ucv)
+		assert.Equal(t, "list", result.AttShape)
+	})
+
+	t.Run("0.10 JWT with att map", func(t *testing.T) {
+		jwt, err := fixtures.GenerateJWT010UCAN()
+		require.NoError(t, err)
+
+		result := parse(t, jwt)
+		assert.Equal(t, "0.10", result.Version)
+		assert.Equal(t, "map", result.AttShape)
+		require.NotNil(t, result.Header)
+		assert.Empty(t, result.Header.Ucv)
+		require.Len(t, result.Capabilities, 1)
+		assert.Equal(t, "store/add", result.Capabilities[0].Can)
+		assert.Equal(t, "storage:alice/*", result.Capabilities[0].With)
+		assert.Len(t, result.Facts, 1)
+		assert.True(t, result.Signature.Valid)
+
+		assert.True(t, validate(t, jwt).Valid)
+	})
+
+	t.Run("Other generations", func(t *testing.T) {
+		archive, err := fixtures.GenerateValidUCAN()
+		require.NoError(t, err)
+		assert.Equal(t, "0.9", parse(t, base64.StdEncoding.EncodeToString(archive)).Version)
+
+		envelope, err := fixtures.GenerateDelegationEnvelope()
+		require.NoError(t, err)
+		assert.Equal(t, "1.0-rc", parse(t, base64.StdEncoding.EncodeToString(envelope)).Version)
+
+		inline, err := fixtures.GenerateInlineJWTChain()
+		require.NoError(t, err)
+		assert.Equal(t, "0.8", parse(t, inline).Version)
+	})
+
+	t.Run("Mixed-version chain is flagged", func(t *testing.T) {
+		bundle, err := fixtures.GenerateMixedVersionChain()
+		require.NoError(t, err)
+
+		result := validate(t, bundle)
+		assert.True(t, result.Valid)
+		assert.True(t, result.MixedVersions)
+		assert.Equal(t, []string{"0.10", "0.9"}, result.Versions)
+		require.Len(t, result.Chain, 2)
+		assert.True(t, hasIssue(result.Chain[0], "mixed_versions"))
+	})
+
+	t.Run("Declared version with the wrong att shape", func(t *testing.T) {
+		jwt, err := fixtures.GenerateMislabelledJWT()
+		require.NoError(t, err)
+
+		result := validate(t, jwt)
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "att_shape_mismatch", result.RootCause.Type)
+		assert.False(t, result.MixedVersions)
+	})
+}
+
+func TestCARInspector(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	inspect := func(t *testing.T, archive []byte) models.CARInspection {
+		var result models.CARInspection
+		resp := postJSON(t, server.URL+"/api/inspect/car", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(archive),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return result
+	}
+	block := func(t *testing.T, result models.CARInspection, id string) models.BlockInfo {
+		for _, blk := range result.Blocks {
+			if blk.CID == id {
+				return blk
+			}
+		}
+		require.Failf(t, "block not found", "%s", id)
+		return models.BlockInfo{}
+	}
+
+	t.Run("Blocks of a chain", func(t *testing.T) {
+		archive, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		result := inspect(t, archive)
+		assert.Equal(t, uint64(1), result.Version)
+		assert.Equal(t, len(archive), result.TotalSize)
+		require.Len(t, result.Roots, 1)
+		require.Len(t, result.Delegations, 2)
+
+		for _, blk := range result.Blocks {
+			assert.Equal(t, "dag-cbor", blk.Codec)
+			assert.Equal(t, "sha2-256", blk.Multihash)
+			assert.True(t, blk.HashMatch, blk.CID)
+			assert.Positive(t, blk.Size)
+			assert.Empty(t, blk.DecodeError)
+			assert.True(t, json.Valid(blk.Content))
+			assert.Equal(t, blk.CID == result.Roots[0], blk.IsRoot)
+		}
+		assert.True(t, block(t, result, result.Roots[0]).IsRoot)
+
+		// The leaf cites the proof, which is referenced by it in turn
+		var leaf, proof models.BlockDelegation
+		for _, del := range result.Delegations {
+			if len(del.Proofs) > 0 {
+				leaf = del
+			} else {
+				proof = del
+			}
+		}
+		require.Equal(t, []string{proof.CID}, leaf.Proofs)
+		assert.Contains(t, leaf.References, proof.CID)
+		assert.Equal(t, []string{leaf.CID}, block(t, result, proof.CID).ReferencedBy)
+		assert.Empty(t, block(t, result, leaf.CID).ReferencedBy)
+		assert.Contains(t, block(t, result, result.Roots[0]).Links, leaf.CID)
+	})
+
+	t.Run("Tampered block", func(t *testing.T) {
+		archive, proofCID, err := fixtures.GenerateTamperedCAR()
+		require.NoError(t, err)
+
+		result := inspect(t, archive)
+		for _, blk := range result.Blocks {
+			assert.Equal(t, blk.CID != proofCID, blk.HashMatch, blk.CID)
+		}
+	})
+
+	t.Run("Not a CAR", func(t *testing.T) {
+		jwt, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+
+		resp := postJSON(t, server.URL+"/api/inspect/car", models.ParseRequest{Token: jwt}, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("CARv2 payload pointing at itself", func(t *testing.T) {
+		archive := fixtures.GenerateSelfReferencingCARv2()
+		require.Len(t, archive, 51)
+
+		resp := postJSON(t, server.URL+"/api/inspect/car", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(archive),
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestBlockIntegrity(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	archive, proofCID, err := fixtures.GenerateTamperedCAR()
+	require.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(archive)
+
+	t.Run("Validation reports the tampered proof", func(t *testing.T) {
+		var result models.ValidationResult
+		resp := postJSON(t, server.URL+"/api/validate/chain", models.ValidateRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, result.Valid)
+		require.NotNil(t, result.RootCause)
+		assert.Equal(t, "block_integrity", result.RootCause.Type)
+		assert.Contains(t, result.RootCause.Message, proofCID)
+
+		require.Len(t, result.Chain, 1)
+		var found bool
+		for _, issue := range result.Chain[0].Issues {
+			assert.NotEqual(t, "missing_proof", issue.Type)
+			if issue.Type == "block_integrity" {
+				found = true
+				assert.Equal(t, proofCID, issue.Context["cid"])
+				assert.NotEqual(t, proofCID, issue.Context["computed"])
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Parse lists the failed block", func(t *testing.T) {
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, result.Integrity, 1)
+		assert.Equal(t, proofCID, result.Integrity[0].CID)
+		require.Len(t, result.Proofs, 1)
+		assert.False(t, result.Proofs[0].Resolved)
+	})
+
+	t.Run("Untampered chain", func(t *testing.T) {
+		chain, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var result models.DelegationResponse
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(chain),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, result.Integrity)
+	})
+
+	t.Run("CARv2 payload pointing at itself", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{
+			Token: base64.StdEncoding.EncodeToString(fixtures.GenerateSelfReferencingCARv2()),
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestBundles(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("Multi-root CAR", func(t *testing.T) {
+		bundle, err := fixtures.GenerateBundleCAR()
+		require.NoError(t, err)
+
+		var result models.BundleResponse
+		resp := postJSON(t, server.URL+"/api/bundle", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(bundle),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, result.Roots, 2)
+		assert.Equal(t, "archive", result.Roots[0].Source)
+		assert.Equal(t, "delegation", result.Roots[1].Source)
+		assert.Equal(t, result.Roots[1].Root, result.Roots[1].CID)
+		assert.NotEqual(t, result.Roots[0].Root, result.Roots[0].CID)
+		for _, root := range result.Roots {
+			require.NotNil(t, root.Validation)
+			assert.True(t, root.Validation.Valid)
+			require.NotNil(t, root.Graph)
+		}
+		assert.Len(t, result.Roots[0].DAG.Delegations, 2)
+		assert.Len(t, result.Roots[1].DAG.Delegations, 1)
+
+		// Alice issues under both roots
+		alice := result.Roots[1].DAG.RootDelegation().Issuer
+		assert.Equal(t, []string{alice}, result.Graph.SharedPrincipals)
+
+		var merged int
+		for _, node := range result.Graph.Nodes {
+			if node.ID == alice {
+				merged++
+				assert.Equal(t, true, node.Metadata["shared"])
+				assert.Len(t, node.Metadata["roots"], 2)
+			}
+		}
+		assert.Equal(t, 1, merged)
+	})
+
+	t.Run("Agent message", func(t *testing.T) {
+		msg, err := fixtures.GenerateAgentMessage()
+		require.NoError(t, err)
+
+		var result models.BundleResponse
+		resp := postJSON(t, server.URL+"/api/bundle", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(msg),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, result.Roots, 1)
+		assert.Equal(t, "agent_message", result.Roots[0].Source)
+	})
+
+	t.Run("Single token", func(t *testing.T) {
+		token, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+
+		var result models.BundleResponse
+		resp := postJSON(t, server.URL+"/api/bundle", models.ValidateRequest{Token: token}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, result.Roots, 1)
+		assert.Equal(t, "token", result.Roots[0].Source)
+		assert.Empty(t, result.Graph.SharedPrincipals)
+	})
+
+	t.Run("Not a delegation", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/bundle", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString([]byte("not a token")),
+		}, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+}
+
+func TestConvert(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	archive, err := fixtures.GenerateComplexChain()
+	require.NoError(t, err)
+	carToken := base64.StdEncoding.EncodeToString(archive)
+
+	var original models.DelegationResponse
+	resp := postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{Token: carToken}, &original)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	t.Run("CAR through DAG-JSON and back", func(t *testing.T) {
+		var doc models.ConvertResponse
+		resp := postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: carToken, To: "dag-json"}, &doc)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "car", doc.From)
+		assert.Empty(t, doc.Encoding)
+		assert.Equal(t, 3, doc.Blocks)
+
+		var back models.ConvertResponse
+		resp = postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: doc.Output, To: "car"}, &back)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "dag-json", back.From)
+		assert.Equal(t, "base64", back.Encoding)
+		assert.Equal(t, doc.Roots, back.Roots)
+		assert.Equal(t, carToken, back.Output)
+
+		var parsed models.DelegationResponse
+		resp = postJSON(t, server.URL+"/api/parse/delegation", models.ParseRequest{Token: back.Output}, &parsed)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, original.CID, parsed.CID)
+	})
+
+	t.Run("CAR to a single DAG-CBOR block", func(t *testing.T) {
+		var result models.ConvertResponse
+		resp := postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: carToken, To: "dag-cbor"}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{original.CID}, result.Roots)
+		assert.Len(t, result.Warnings, 1)
+
+		var back models.ConvertResponse
+		resp = postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: result.Output, To: "car"}, &back)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "dag-cbor", back.From)
+		assert.Equal(t, []string{original.CID}, back.Roots)
+		assert.Equal(t, 1, back.Blocks)
+	})
+
+	t.Run("JWT round trip", func(t *testing.T) {
+		jwt, err := fixtures.GenerateJWTUCAN()
+		require.NoError(t, err)
+		id, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: multihash.SHA2_256, MhLength: -1}.Sum([]byte(jwt))
+		require.NoError(t, err)
+
+		var archived models.ConvertResponse
+		resp := postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: jwt, To: "car"}, &archived)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "jwt", archived.From)
+		assert.Equal(t, []string{id.String()}, archived.Roots)
+
+		var back models.ConvertResponse
+		resp = postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: archived.Output, To: "jwt"}, &back)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, jwt, back.Output)
+
+		resp = postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: jwt, To: "dag-cbor"}, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("Multibase", func(t *testing.T) {
+		var result models.ConvertResponse
+		resp := postJSON(t, server.URL+"/api/convert", models.ConvertRequest{
+			Token: carToken,
+			To:    "multibase",
+			Base:  "base32",
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		encoding, data, err := multibase.Decode(result.Output)
+		require.NoError(t, err)
+		assert.Equal(t, multibase.Encoding(multibase.Base32), encoding)
+		assert.Equal(t, archive, data)
+	})
+
+	t.Run("Bare DAG-JSON node to base64", func(t *testing.T) {
+		node := `{"can":"store/add","with":"did:key:z6Mkalice"}`
+
+		var archived models.ConvertResponse
+		resp := postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: node, To: "car"}, &archived)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result models.ConvertResponse
+		resp = postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: node, To: "base64"}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "dag-json", result.From)
+		assert.Equal(t, archived.Roots, result.Roots)
+		assert.Equal(t, 1, result.Blocks)
+		assert.Equal(t, archived.Output, result.Output)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		body, err := json.Marshal(models.ConvertRequest{Token: carToken, To: "car", Download: true})
+		require.NoError(t, err)
+		resp, err := http.Post(server.URL+"/api/convert", "application/json", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/vnd.ipld.car", resp.Header.Get("Content-Type"))
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, archive, buf.Bytes())
+	})
+
+	t.Run("Unsupported output", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/convert", models.ConvertRequest{Token: carToken, To: "xml"}, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("CARv2 payload pointing at itself", func(t *testing.T) {
+		resp := postJSON(t, server.URL+"/api/convert", models.ConvertRequest{
+			Token: base64.StdEncoding.EncodeToString(fixtures.GenerateSelfReferencingCARv2()),
+			To:    "dag-json",
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+// postExport sends a graph request with the given Accept header and returns
+// the response body
+func postExport(t *testing.T, url string, payload interface{}, accept string) (*http.Response, string) {
+	t.Helper()
+
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	return resp, buf.String()
+}
+
+func TestGraphExport(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	chain, err := fixtures.GenerateComplexChain()
+	require.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(chain)
+	url := server.URL + "/api/graph/delegation"
+
+	var graph models.GraphResponse
+	resp := postJSON(t, url, models.GraphRequest{Token: token}, &graph)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	t.Run("DOT", func(t *testing.T) {
+		resp, body := postExport(t, url, models.GraphRequest{Token: token, Export: "dot"}, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/vnd.graphviz", resp.Header.Get("Content-Type"))
+
+		assert.True(t, strings.HasPrefix(body, "digraph delegation {"))
+		assert.Contains(t, body, `fillcolor="#6366f1", class="root"`)
+		for _, edge := range graph.Edges {
+			assert.Contains(t, body, fmt.Sprintf("label=%q", edge.Label))
+		}
+	})
+
+	t.Run("Mermaid through Accept", func(t *testing.T) {
+		resp, body := postExport(t, url, models.GraphRequest{Token: token}, "text/vnd.mermaid")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, strings.HasPrefix(body, "flowchart TD\n"))
+		assert.Contains(t, body, "classDef ucan_root fill:#6366f1")
+		assert.NotContains(t, body, "-.->") // no invalid edges or missing proofs
+	})
+
+	t.Run("GraphML", func(t *testing.T) {
+		resp, body := postExport(t, url+"?export=graphml", models.GraphRequest{Token: token}, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var doc struct {
+			Graph struct {
+				Nodes []struct {
+					ID string `xml:"id,attr"`
+				} `xml:"node"`
+				Edges []struct {
+					Source string `xml:"source,attr"`
+					Target string `xml:"target,attr"`
+				} `xml:"edge"`
+			} `xml:"graph"`
+		}
+		require.NoError(t, xml.Unmarshal([]byte(body), &doc))
+		assert.Len(t, doc.Graph.Edges, len(graph.Edges))
+
+		ids := make(map[string]bool)
+		for _, node := range doc.Graph.Nodes {
+			ids[node.ID] = true
+		}
+		for _, edge := range doc.Graph.Edges {
+			assert.True(t, ids[edge.Source], "edge source %s is declared", edge.Source)
+			assert.True(t, ids[edge.Target], "edge target %s is declared", edge.Target)
+		}
+	})
+
+	t.Run("Cytoscape", func(t *testing.T) {
+		resp, body := postExport(t, url, models.GraphRequest{Token: token, Export: "cytoscape"}, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var doc struct {
+			Elements struct {
+				Nodes []struct {
+					Data map[string]interface{} `json:"data"`
+				} `json:"nodes"`
+				Edges []struct {
+					Data    map[string]interface{} `json:"data"`
+					Classes string                 `json:"classes"`
+				} `json:"edges"`
+			} `json:"elements"`
+			Style []interface{} `json:"style"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &doc))
+		assert.Len(t, doc.Elements.Edges, len(graph.Edges))
+		assert.NotEmpty(t, doc.Style)
+
+		var delegations int
+		for _, edge := range doc.Elements.Edges {
+			if edge.Data["type"] == "delegation" {
+				delegations++
+				assert.Equal(t, "#6366f1", edge.Data["color"])
+				assert.NotEmpty(t, edge.Data["capability"])
+				assert.Contains(t, edge.Classes, "valid")
+			}
+		}
+		assert.Greater(t, delegations, 0)
+	})
+
+	t.Run("Expired delegation in the error colour", func(t *testing.T) {
+		expired, err := fixtures.GenerateExpiredUCAN()
+		require.NoError(t, err)
+		expiredToken := base64.StdEncoding.EncodeToString(expired)
+
+		var expiredGraph models.GraphResponse
+		resp := postJSON(t, url, models.GraphRequest{Token: expiredToken}, &expiredGraph)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NotEmpty(t, expiredGraph.Edges)
+		for _, edge := range expiredGraph.Edges {
+			assert.False(t, edge.Valid, edge.Label)
+		}
+
+		resp, body := postExport(t, url, models.GraphRequest{Token: expiredToken, Export: "dot"}, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, `color="#ef4444", fontcolor="#ef4444", style=dashed, class="delegation invalid dashed"`)
+
+		resp, body = postExport(t, url, models.GraphRequest{Token: expiredToken, Export: "mermaid"}, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "linkStyle 0 stroke:#ef4444")
+	})
+
+	t.Run("Unsupported export", func(t *testing.T) {
+		resp, _ := postExport(t, url, models.GraphRequest{Token: token, Export: "png"}, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("JSON by default", func(t *testing.T) {
+		resp, _ := postExport(t, url, models.GraphRequest{Token: token}, "text/html, */*")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	})
+}
+
+func TestRenderDelegation(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	chain, err := fixtures.GenerateComplexChain()
+	require.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(chain)
+	url := server.URL + "/api/render/delegation"
+
+	var graph models.GraphResponse
+	resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{Token: token}, &graph)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	t.Run("SVG by default", func(t *testing.T) {
+		resp, body := postExport(t, url, models.RenderRequest{Token: token}, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/svg+xml", resp.Header.Get("Content-Type"))
+
+		var doc struct {
+			XMLName xml.Name
+			Groups  []struct {
+				Class string `xml:"class,attr"`
+				Title string `xml:"title"`
+			} `xml:"g"`
+		}
+		require.NoError(t, xml.Unmarshal([]byte(body), &doc))
+		assert.Equal(t, "svg", doc.XMLName.Local)
+
+		titles := make(map[string]bool)
+		var edges int
+		for _, g := range doc.Groups {
+			if strings.HasPrefix(g.Class, "node ") {
+				titles[g.Title] = true
+			} else {
+				edges++
+			}
+		}
+		for _, node := range graph.Nodes {
+			assert.True(t, titles[node.ID], "node %s is drawn", node.ID)
+		}
+		assert.Equal(t, len(graph.Edges), edges)
+		assert.Contains(t, body, `class="node root"`)
+		assert.Contains(t, body, `stroke="#6366f1"`)
+	})
+
+	t.Run("PNG through Accept", func(t *testing.T) {
+		resp, body := postExport(t, url, models.RenderRequest{Token: token}, "image/png")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+
+		img, err := png.Decode(strings.NewReader(body))
+		require.NoError(t, err)
+		assert.Greater(t, img.Bounds().Dx(), 0)
+		assert.Greater(t, img.Bounds().Dy(), 0)
+	})
+
+	t.Run("Unsupported image", func(t *testing.T) {
+		resp, _ := postExport(t, url, models.RenderRequest{Token: token, Image: "gif"}, "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("File uploads are checked like graph uploads", func(t *testing.T) {
+		upload := func(t *testing.T, path, filename string) *http.Response {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile("file", filename)
+			require.NoError(t, err)
+			_, err = part.Write(chain)
+			require.NoError(t, err)
+			require.NoError(t, form.Close())
+
+			resp, err := http.Post(server.URL+path, form.FormDataContentType(), &body)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+
+		for _, path := range []string{"/api/graph/delegation/file", "/api/render/delegation/file"} {
+			assert.Equal(t, http.StatusOK, upload(t, path, "chain.car").StatusCode, path)
+			assert.Equal(t, http.StatusBadRequest, upload(t, path, "chain.png").StatusCode, path)
+		}
+	})
+}
+
+func TestGraphLayout(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	chain, err := fixtures.GenerateDiamondChain()
+	require.NoError(t, err)
+	token := base64.StdEncoding.EncodeToString(chain)
+
+	var graph models.GraphResponse
+	resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{Token: token}, &graph)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, graph.Nodes)
+
+	t.Run("Nodes are placed on layers", func(t *testing.T) {
+		layers := make(map[string]int)
+		boxes := make(map[[2]float64]bool)
+		for i, node := range graph.Nodes {
+			layers[node.ID] = node.Layer
+			if i > 0 {
+				prev := graph.Nodes[i-1]
+				assert.True(t, prev.Layer < node.Layer || (prev.Layer == node.Layer && prev.X < node.X),
+					"nodes are listed layer by layer, left to right")
+			}
+			assert.False(t, boxes[[2]float64{node.X, node.Y}], "two nodes share a position")
+			boxes[[2]float64{node.X, node.Y}] = true
+		}
+
+		// Authority flows down: every delegation points to a lower layer
+		for _, edge := range graph.Edges {
+			if edge.Type == "delegation" {
+				assert.Less(t, layers[edge.Source], layers[edge.Target])
+			}
+		}
+	})
+
+	t.Run("Same token, same layout", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			var again models.GraphResponse
+			resp := postJSON(t, server.URL+"/api/graph/delegation", models.GraphRequest{Token: token}, &again)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, graph.Nodes, again.Nodes)
+			assert.Equal(t, graph.Edges, again.Edges)
+			assert.Equal(t, graph.Chain.Principals, again.Chain.Principals)
+		}
+	})
+
+	t.Run("Bundles are laid out as a whole", func(t *testing.T) {
+		bundle, err := fixtures.GenerateBundleCAR()
+		require.NoError(t, err)
+
+		var result models.BundleResponse
+		resp := postJSON(t, server.URL+"/api/bundle", models.ValidateRequest{
+			Token: base64.StdEncoding.EncodeToString(bundle),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		boxes := make(map[[2]float64]bool)
+		for _, node := range result.Graph.Nodes {
+			assert.False(t, boxes[[2]float64{node.X, node.Y}], "two nodes share a position")
+			boxes[[2]float64{node.X, node.Y}] = true
+		}
+	})
+}
+
+func TestDelegationGraphMode(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	url := server.URL + "/api/graph/delegation"
+
+	t.Run("Delegations as nodes", func(t *testing.T) {
+		chain, err := fixtures.GenerateDiamondChain()
+		require.NoError(t, err)
+		token := base64.StdEncoding.EncodeToString(chain)
+
+		var dag models.DelegationDAG
+		resp := postJSON(t, server.URL+"/api/parse/chain", models.ParseRequest{Token: token}, &dag)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result models.GraphResponse
+		resp = postJSON(t, url, models.GraphRequest{Token: token, Mode: "delegations"}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "delegations", result.Mode)
+
+		nodes := make(map[string]models.GraphNode)
+		for _, node := range result.Nodes {
+			nodes[node.ID] = node
+		}
+		require.Len(t, nodes, len(dag.Delegations))
+		for cid, del := range dag.Delegations {
+			node, ok := nodes[cid]
+			require.True(t, ok, "no node for delegation %s", cid)
+			assert.Equal(t, del.Issuer, node.Metadata["issuer"])
+			assert.Equal(t, del.Audience, node.Metadata["audience"])
+			assert.NotEmpty(t, node.Metadata["capabilities"])
+			assert.Equal(t, true, node.Metadata["valid"])
+		}
+		assert.Equal(t, "root", nodes[dag.Root].Type)
+
+		// One edge per citation, each joining two delegations
+		require.Len(t, result.Edges, len(dag.Edges))
+		for _, edge := range result.Edges {
+			assert.Equal(t, "proves", edge.Type)
+			assert.True(t, edge.Valid)
+			assert.Contains(t, nodes, edge.Source)
+			assert.Contains(t, nodes, edge.Target)
+			assert.Less(t, nodes[edge.Source].Layer, nodes[edge.Target].Layer)
+		}
+	})
+
+	t.Run("Missing proof", func(t *testing.T) {
+		chain, err := fixtures.GenerateChainWithoutProofs()
+		require.NoError(t, err)
+
+		var result models.GraphResponse
+		resp := postJSON(t, url+"?mode=delegations", models.GraphRequest{
+			Token: base64.StdEncoding.EncodeToString(chain),
+		}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, result.Chain.UnresolvedProofs, 1)
+		missing := result.Chain.UnresolvedProofs[0].CID
+		require.Len(t, result.Edges, 1)
+		assert.Equal(t, missing, result.Edges[0].Source)
+		assert.False(t, result.Edges[0].Valid)
+
+		var found bool
+		for _, node := range result.Nodes {
+			if node.ID == missing {
+				found = true
+				assert.Equal(t, "unresolved", node.Type)
+			} else {
+				assert.Equal(t, false, node.Metadata["valid"])
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Principals by default", func(t *testing.T) {
+		chain, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var result models.GraphResponse
+		resp := postJSON(t, url, models.GraphRequest{Token: base64.StdEncoding.EncodeToString(chain)}, &result)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "principals", result.Mode)
+	})
+
+	t.Run("Unknown mode", func(t *testing.T) {
+		resp := postJSON(t, url, models.GraphRequest{Token: "irrelevant", Mode: "capabilities"}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestCapabilityTrace(t *testing.T) {
+	handler := api.SetupRouter()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	url := server.URL + "/api/graph/trace"
+
+	t.Run("Granted through attenuation", func(t *testing.T) {
+		chain, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var trace models.CapabilityTrace
+		resp := postJSON(t, url, models.TraceRequest{
+			Token: base64.StdEncoding.EncodeToString(chain),
+			Can:   "store/add",
+			With:  "storage:alice/photos",
+		}, &trace)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.True(t, trace.Granted)
+		assert.Nil(t, trace.Break)
+		require.Len(t, trace.Paths, 1)
+		hops := trace.Paths[0].Hops
+		require.Len(t, hops, 2)
+		assert.Equal(t, "origin", hops[0].Status)
+		assert.Equal(t, "store/*", hops[0].Capability.Can)
+		assert.Equal(t, "attenuated", hops[1].Status)
+		assert.Equal(t, "store/add", hops[1].Capability.Can)
+		assert.Equal(t, "storage:alice/*", hops[1].Capability.With)
+		require.NotNil(t, hops[1].Attenuation)
+		assert.True(t, hops[1].Attenuation.Valid)
+		assert.Equal(t, trace.Principal, hops[1].Audience)
+
+		// Only the capability edges carrying the trace remain
+		require.Len(t, trace.Edges, 2)
+		assert.Len(t, trace.Nodes, 3)
+		for _, edge := range trace.Edges {
+			assert.True(t, edge.Valid)
+			assert.NotEqual(t, "proof", edge.Type)
+		}
+	})
+
+	t.Run("Broadened hop", func(t *testing.T) {
+		chain, err := fixtures.GenerateEscalatedChain()
+		require.NoError(t, err)
+
+		var trace models.CapabilityTrace
+		resp := postJSON(t, url, models.TraceRequest{
+			Token: base64.StdEncoding.EncodeToString(chain),
+			Can:   "store/add",
+			With:  "storage:alice/photos",
+		}, &trace)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// Bob holds store/add but passes on store/*
+		assert.False(t, trace.Granted)
+		require.Len(t, trace.Paths, 1)
+		hops := trace.Paths[0].Hops
+		require.Len(t, hops, 2)
+		assert.Equal(t, "origin", hops[0].Status)
+		assert.Equal(t, "broadened", hops[1].Status)
+		assert.Equal(t, "store/*", hops[1].Capability.Can)
+		require.NotNil(t, hops[1].Attenuation)
+		assert.False(t, hops[1].Attenuation.AbilityMatch)
+
+		require.NotNil(t, trace.Break)
+		assert.Equal(t, hops[1].CID, trace.Break.CID)
+		assert.Equal(t, trace.Break.Issue, trace.Reason)
+		require.Len(t, trace.Edges, 2)
+		for _, edge := range trace.Edges {
+			assert.Equal(t, edge.Metadata["trace"] != "broadened", edge.Valid)
+		}
+	})
+
+	t.Run("Not carried", func(t *testing.T) {
+		chain, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var trace models.CapabilityTrace
+		resp := postJSON(t, url, models.TraceRequest{
+			Token: base64.StdEncoding.EncodeToString(chain),
+			Can:   "upload/add",
+		}, &trace)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, trace.Granted)
+		assert.Nil(t, trace.Break)
+		assert.NotEmpty(t, trace.Reason)
+		assert.Empty(t, trace.Edges)
+	})
+
+	t.Run("Unknown principal", func(t *testing.T) {
+		chain, err := fixtures.GenerateComplexChain()
+		require.NoError(t, err)
+
+		var trace models.CapabilityTrace
+		resp := postJSON(t, url, models.TraceRequest{
+			Token:     base64.StdEncoding.EncodeToString(chain),
+			Principal: "did:key:z6MkNobody",
+			Can:       "store/add",
+		}, &trace)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.False(t, trace.Granted)
+		assert.Empty(t, trace.Paths)
+		assert.Contains(t, trace.Reason, "did:key:z6MkNobody")
+	})
+
+	t.Run("Ability required", func(t *testing.T) {
+		resp := postJSON(t, url, models.TraceRequest{Token: "irrelevant"}, nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}